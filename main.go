@@ -1,34 +1,196 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"go-cli/pkg/libcli"
+	"go-cli/pkg/libhttp"
 	"go-cli/pkg/libnet"
+	"go-cli/pkg/libssh"
+	"go-cli/pkg/libutil"
 	"go-cli/pkg/libvm"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	nblogger "github.com/banaconda/nb-logger"
 )
 
-func main() {
-	go libnet.NetServer()
-	go libvm.VmServer()
+// get logger of cli and clients or exit
+func newCliLogger(config *libutil.Config) nblogger.Logger {
+	cliLogger, err := nblogger.NewLogger(config.GetLogPath("cli.log"), nblogger.Info, 1000,
+		nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger init fail: %v\n", err)
+		os.Exit(1)
+	}
+
+	return cliLogger
+}
 
-	cliLogPath := "log/cli.log"
-	if _, err := os.Stat("log/"); os.IsNotExist(err) {
-		os.MkdirAll("log", 0700)
+// load config overridden by override or exit
+func loadConfig(path string, override func(config *libutil.Config)) *libutil.Config {
+	config, err := libutil.LoadConfig(path, override)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config: %v\n", err)
+		os.Exit(1)
 	}
 
-	cliLogger, err := nblogger.NewLogger(cliLogPath, nblogger.Info, 1000,
-		nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
+	return config
+}
+
+// run networker and vmer services until SIGTERM or SIGINT
+func runDaemon(args []string) {
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	configPath := flags.String("config", "", "config file path (default "+libutil.CONFIG_PATH+" if exists)")
+	aliasDir := flags.String("alias-dir", "", "directory of alias and macro files of ssh users, a file per user (default <log_dir>/ssh_alias)")
+	serveSsh := flags.Bool("ssh", false, "serve cli over ssh")
+	serveHttp := flags.Bool("http", false, "serve rest api over http, or https if tls is on")
+	flags.Parse(args)
+
+	config := loadConfig(*configPath, nil)
+
+	// services do not start without certificate of daemon
+	if config.Tls {
+		if _, err := config.GetServerTlsConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid tls: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// in-process clis of ssh sessions and http gateway forward identity of callers by token of daemon
+	if err := config.InitDaemonToken(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate token of daemon: %v\n", err)
+		os.Exit(1)
+	}
+
+	// audit logs of both services are stored in db of vmer
+	auditDB, err := libutil.NewAuditDB(config.DbPath)
 	if err != nil {
-		fmt.Printf("logger init fail: %v", err)
+		fmt.Fprintf(os.Stderr, "failed to open audit db: %v\n", err)
+		os.Exit(1)
+	}
+
+	go libnet.NetServer(config, auditDB)
+	go libvm.VmServer(config, auditDB)
+
+	// cli of ssh session does not save history, and calls as user of session.
+	// each user has own alias file, so sessions of users do not share aliases
+	if *serveSsh {
+		if *aliasDir == "" {
+			*aliasDir = config.GetLogPath("ssh_alias")
+		}
+		if err := os.MkdirAll(*aliasDir, 0700); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to make alias dir: %v\n", err)
+			os.Exit(1)
+		}
+
+		cliLogger := newCliLogger(config)
+		go libssh.SshServer(config, func(user string) (*libcli.GoCli, error) {
+			if user == "." || user == ".." || filepath.Base(user) != user {
+				return nil, fmt.Errorf("invalid user name \"%s\" for alias file", user)
+			}
+
+			cli := libcli.GoCli{
+				AliasPath: filepath.Join(*aliasDir, user),
+			}
+			cli.Init(cliLogger)
+			if err := libnet.InitCli(&cli, config, user, cliLogger); err != nil {
+				return nil, err
+			}
+			if err := libvm.InitCli(&cli, config, user, cliLogger); err != nil {
+				return nil, err
+			}
+			return &cli, nil
+		})
+	}
+
+	if *serveHttp {
+		go libhttp.HttpServer(config)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	fmt.Printf("%v received, shutting down\n", <-signals)
+
+	libssh.SshServerStop()
+	libhttp.HttpServerStop()
+	libnet.NetServerStop()
+	libvm.VmServerStop()
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "daemon" {
+		runDaemon(os.Args[2:])
+		return
+	}
+
+	command := flag.String("c", "", "run command and exit")
+	scriptPath := flag.String("f", "", "run commands from file (- for stdin) and exit")
+	continueOnError := flag.Bool("k", false, "keep running the script when a command fails")
+	historyPath := flag.String("history", "", "history file path (default <log_dir>/cli_history)")
+	historySize := flag.Int("history-size", 1000, "max number of history lines")
+	aliasPath := flag.String("alias", "", "alias and macro file path (default <log_dir>/cli_alias)")
+	configPath := flag.String("config", "", "config file path (default "+libutil.CONFIG_PATH+" if exists)")
+	host := flag.String("host", "", "connect to daemon on host by tcp instead of unix sockets")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags]\n       %s daemon [daemon flags]\n",
+			os.Args[0], os.Args[0])
+		flag.PrintDefaults()
 	}
+	flag.Parse()
 
-	cli := libcli.GoCli{}
+	config := loadConfig(*configPath, func(config *libutil.Config) {
+		if *host != "" {
+			config.Tcp = true
+			config.Host = *host
+		}
+	})
+	if *historyPath == "" {
+		*historyPath = config.GetLogPath("cli_history")
+	}
+	if *aliasPath == "" {
+		*aliasPath = config.GetLogPath("cli_alias")
+	}
+
+	cli := libcli.GoCli{
+		HistoryPath: *historyPath,
+		HistorySize: *historySize,
+		AliasPath:   *aliasPath,
+	}
+	cliLogger := newCliLogger(config)
 	cli.Init(cliLogger)
-	libnet.InitCli(&cli)
-	libvm.InitCli(&cli)
+	if err := libnet.InitCli(&cli, config, "", cliLogger); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if err := libvm.InitCli(&cli, config, "", cliLogger); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
 
-	cli.Run()
+	var scriptErr error
+	switch {
+	case *command != "":
+		scriptErr = cli.RunScript(strings.NewReader(*command), *continueOnError)
+	case *scriptPath == "-":
+		scriptErr = cli.RunScript(os.Stdin, *continueOnError)
+	case *scriptPath != "":
+		file, err := os.Open(*scriptPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		scriptErr = cli.RunScript(file, *continueOnError)
+		file.Close()
+	default:
+		cli.Run()
+	}
+
+	if scriptErr != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", scriptErr)
+		os.Exit(1)
+	}
 }
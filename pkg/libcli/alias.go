@@ -0,0 +1,252 @@
+package libcli
+
+import (
+	"fmt"
+	"go-cli/pkg/libutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	typeAlias = "alias"
+	typeMacro = "macro"
+	// max depth of alias using alias
+	maxAliasDepth = 8
+)
+
+// $1 style argument of alias
+var aliasArgRegexp = regexp.MustCompile(`\$([0-9]+)`)
+
+// alias expands to a command and macro expands to commands separated by ";".
+// $N is substituted by the nth argument.
+// eg. # alias web domain create name $1 bridge br0
+type alias struct {
+	Type string
+	Name string
+	Line string
+}
+
+// get command lines of alias
+func (a *alias) getLines() []string {
+	if a.Type == typeAlias {
+		return []string{a.Line}
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(a.Line, ";") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// expand alias by args. args not used by $N are appended to alias
+func (a *alias) expand(args []string) ([]string, error) {
+	used := 0
+	var expandErr error
+	lines := make([]string, 0)
+	for _, line := range a.getLines() {
+		line = aliasArgRegexp.ReplaceAllStringFunc(line, func(arg string) string {
+			index, _ := strconv.Atoi(arg[1:])
+			if index < 1 || index > len(args) {
+				expandErr = fmt.Errorf("%s %s: missing argument %s", a.Type, a.Name, arg)
+				return arg
+			}
+			if index > used {
+				used = index
+			}
+			return args[index-1]
+		})
+		lines = append(lines, line)
+	}
+
+	if expandErr != nil {
+		return nil, expandErr
+	}
+
+	if used < len(args) {
+		if a.Type == typeMacro {
+			return nil, fmt.Errorf("%s %s: too many arguments", a.Type, a.Name)
+		}
+		lines[0] = fmt.Sprintf("%s %s", lines[0], strings.Join(args[used:], " "))
+	}
+
+	return lines, nil
+}
+
+// load aliases from alias file
+func (cli *GoCli) loadAlias() error {
+	if cli.AliasPath == "" || !libutil.IsExist(cli.AliasPath) {
+		return nil
+	}
+
+	data, err := libutil.ReadFile(cli.AliasPath)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		args := strings.Fields(line)
+		if len(args) < 3 {
+			continue
+		}
+
+		if err := cli.defineAlias(args[0], args[1], strings.Join(args[2:], " ")); err != nil {
+			cli.logger.Warn("failed to load %s: %v", line, err)
+		}
+	}
+
+	return nil
+}
+
+// save aliases to alias file
+func (cli *GoCli) saveAlias() error {
+	if cli.AliasPath == "" {
+		return nil
+	}
+
+	var sb strings.Builder
+	for _, a := range cli.getAliasList("") {
+		sb.WriteString(fmt.Sprintf("%s %s %s\n", a.Type, a.Name, a.Line))
+	}
+
+	return os.WriteFile(cli.AliasPath, []byte(sb.String()), 0600)
+}
+
+// get aliases of type sorted by name. all types if empty
+func (cli *GoCli) getAliasList(aliasType string) []*alias {
+	aliasSlice := make([]*alias, 0)
+	for _, a := range cli.aliasMap {
+		if aliasType == "" || a.Type == aliasType {
+			aliasSlice = append(aliasSlice, a)
+		}
+	}
+	sort.Slice(aliasSlice, func(i, j int) bool { return aliasSlice[i].Name < aliasSlice[j].Name })
+
+	return aliasSlice
+}
+
+// define alias as command of root. alias can not replace existing command
+func (cli *GoCli) defineAlias(aliasType string, name string, line string) error {
+	if aliasType != typeAlias && aliasType != typeMacro {
+		return fmt.Errorf("unknown type \"%s\"", aliasType)
+	}
+
+	if err := KindName.validate(name); err != nil {
+		return err
+	}
+
+	if _, ok := cli.aliasMap[name]; !ok && cli.commandMap[name] != nil {
+		return fmt.Errorf("command \"%s\" already exists", name)
+	}
+
+	a := &alias{Type: aliasType, Name: name, Line: line}
+	cli.aliasMap[name] = a
+
+	run := func(args []string) error {
+		return cli.runAlias(a, args[1:])
+	}
+
+	delete(cli.commandMap, name)
+	cli.AddCommandElem(
+		NewCommandElem(name, fmt.Sprintf("%s of \"%s\"", aliasType, line), run))
+	cli.AddCommandElem(
+		NewCommandElemWithoutFunc(name, ""),
+		NewArgElem(KindLine, fmt.Sprintf("arguments of %s", aliasType), run))
+
+	return nil
+}
+
+// delete alias from command of root
+func (cli *GoCli) deleteAlias(aliasType string, name string) error {
+	a := cli.aliasMap[name]
+	if a == nil || a.Type != aliasType {
+		return fmt.Errorf("%s \"%s\" does not exist", aliasType, name)
+	}
+
+	delete(cli.aliasMap, name)
+	delete(cli.commandMap, name)
+
+	return nil
+}
+
+// run commands expanded from alias
+func (cli *GoCli) runAlias(a *alias, args []string) error {
+	if cli.aliasDepth >= maxAliasDepth {
+		return fmt.Errorf("%s %s: too deep %s expansion", a.Type, a.Name, a.Type)
+	}
+
+	lines, err := a.expand(args)
+	if err != nil {
+		return err
+	}
+
+	cli.aliasDepth++
+	defer func() {
+		cli.aliasDepth--
+	}()
+
+	for _, line := range lines {
+		if err := cli.execLine(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// complete alias names of type
+func (cli *GoCli) completeAlias(aliasType string) func(args []string) []string {
+	return func(args []string) []string {
+		names := make([]string, 0)
+		for _, a := range cli.getAliasList(aliasType) {
+			names = append(names, a.Name)
+		}
+		return names
+	}
+}
+
+// add alias and macro commands
+func (cli *GoCli) aliasCommand() {
+	for _, each := range [][]string{
+		{typeAlias, "define command expanding to a command. $N is the nth argument"},
+		{typeMacro, "define command expanding to commands separated by \";\". $N is the nth argument"},
+	} {
+		aliasType, desc := each[0], each[1]
+
+		// define alias
+		cli.AddCommandElem(
+			NewCommandElemWithoutFunc(aliasType, desc),
+			NewArgElemWithoutFunc(KindName, fmt.Sprintf("name of %s", aliasType)),
+			NewArgElem(KindLine, "command", func(args []string) error {
+				if err := cli.defineAlias(aliasType, args[1], strings.Join(args[2:], " ")); err != nil {
+					return err
+				}
+				return cli.saveAlias()
+			}))
+
+		// show aliases
+		cli.AddCommandElem(
+			NewCommandElemWithoutFunc(aliasType, desc),
+			NewCommandElem("show", fmt.Sprintf("show %ses", aliasType), func(args []string) error {
+				cli.PrintStructAll(cli.getAliasList(aliasType))
+				return nil
+			}))
+
+		// delete alias
+		cli.AddCommandElem(
+			NewCommandElemWithoutFunc(aliasType, desc),
+			NewCommandElemWithoutFunc("delete", fmt.Sprintf("delete %s", aliasType)),
+			NewArgElem(KindName, fmt.Sprintf("name of %s", aliasType), func(args []string) error {
+				if err := cli.deleteAlias(aliasType, args[2]); err != nil {
+					return err
+				}
+				return cli.saveAlias()
+			}).SetCompleter(cli.completeAlias(aliasType)))
+	}
+}
@@ -1,9 +1,10 @@
 package libcli
 
 import (
+	"errors"
 	"fmt"
 	"go-cli/pkg/libutil"
-	"regexp"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -39,64 +40,146 @@ func (cli *GoCli) getIsLastCharEmpty() bool {
 	return cli.getBufLen() == 0 || cli.buf[cli.getBufLen()-1] == ' '
 }
 
+// set cli buf by string and move cursor to the end
+func (cli *GoCli) setBuf(line string) {
+	cli.buf = []rune(line)
+	cli.cursorPos = cli.getBufLen()
+}
+
+// rewrite prompt and cli buf on stdout and put cursor on cursor pos
+func (cli *GoCli) redrawLine() {
+	libutil.FprintClearLine(cli.term)
+	cli.printLine()
+	libutil.FprintMoveCursor(cli.term, -libutil.RunesWidth(cli.buf[cli.cursorPos:]))
+}
+
 // move cursor by direction
 func (cli *GoCli) moveCursor(direction int) {
-	if cli.cursorPos == 0 && direction < 0 || cli.cursorPos >= cli.getBufLen() && direction > 0 {
-		return
+	cli.setCursor(cli.cursorPos + direction)
+}
+
+// set cursor pos in range of cli buf
+func (cli *GoCli) setCursor(pos int) {
+	if pos < 0 {
+		pos = 0
+	} else if pos > cli.getBufLen() {
+		pos = cli.getBufLen()
+	}
+
+	if pos < cli.cursorPos {
+		libutil.FprintMoveCursor(cli.term, -libutil.RunesWidth(cli.buf[pos:cli.cursorPos]))
+	} else {
+		libutil.FprintMoveCursor(cli.term, libutil.RunesWidth(cli.buf[cli.cursorPos:pos]))
 	}
-	libutil.MoveCursor(direction)
 
 	// update cursor pos
-	cli.cursorPos += direction
+	cli.cursorPos = pos
+}
+
+// get start pos of the word before cursor
+func (cli *GoCli) getPrevWordPos() int {
+	pos := cli.cursorPos
+	for pos > 0 && cli.buf[pos-1] == ' ' {
+		pos--
+	}
+	for pos > 0 && cli.buf[pos-1] != ' ' {
+		pos--
+	}
+	return pos
+}
+
+// get end pos of the word after cursor
+func (cli *GoCli) getNextWordPos() int {
+	pos := cli.cursorPos
+	for pos < cli.getBufLen() && cli.buf[pos] == ' ' {
+		pos++
+	}
+	for pos < cli.getBufLen() && cli.buf[pos] != ' ' {
+		pos++
+	}
+	return pos
+}
+
+// delete runes of cli buf in [start, end) and put cursor on start
+func (cli *GoCli) deleteRange(start int, end int) {
+	if start >= end {
+		return
+	}
+
+	cli.buf = append(cli.buf[:start], cli.buf[end:]...)
+	cli.cursorPos = start
+	cli.redrawLine()
 }
 
 // append rune to GoCli buf
 func (cli *GoCli) inputChar(r rune) {
 	cli.logger.Info("cli cursor pos: %d, buf len %d", cli.cursorPos, cli.getBufLen())
 
-	if cli.cursorPos == cli.getBufLen() {
-		cli.buf = append(cli.buf, byte(r))
-	} else {
-		cli.buf = append(cli.buf, 0)
-		copy(cli.buf[cli.cursorPos+1:], cli.buf[cli.cursorPos:])
-		cli.buf[cli.cursorPos] = byte(r)
-	}
-	libutil.ClearLine()
-	fmt.Printf("# %s", cli.buf)
+	cli.buf = append(cli.buf, 0)
+	copy(cli.buf[cli.cursorPos+1:], cli.buf[cli.cursorPos:])
+	cli.buf[cli.cursorPos] = r
 	cli.cursorPos++
 
-	libutil.MoveCursor(cli.cursorPos - cli.getBufLen())
+	cli.redrawLine()
 }
 
 // backspace
 func (cli *GoCli) backspace() {
-	if cli.getBufLen() == 0 {
+	if cli.cursorPos == 0 {
 		return
 	}
 
-	if cli.cursorPos == cli.getBufLen() {
-		cli.buf = cli.buf[:cli.getBufLen()-1]
-	} else {
-		copy(cli.buf[cli.cursorPos-1:], cli.buf[cli.cursorPos:])
-		cli.buf = cli.buf[:cli.getBufLen()-1]
+	cli.deleteRange(cli.cursorPos-1, cli.cursorPos)
+}
+
+// delete rune under cursor
+func (cli *GoCli) deleteChar() {
+	if cli.cursorPos >= cli.getBufLen() {
+		return
 	}
 
-	libutil.ClearLine()
-	fmt.Printf("# %s", cli.buf)
-	cli.cursorPos--
+	cli.deleteRange(cli.cursorPos, cli.cursorPos+1)
+}
 
-	libutil.MoveCursor(cli.cursorPos - cli.getBufLen())
+// delete from cursor to the end of line
+func (cli *GoCli) killLineEnd() {
+	cli.deleteRange(cli.cursorPos, cli.getBufLen())
+}
+
+// delete from the start of line to cursor
+func (cli *GoCli) killLineStart() {
+	cli.deleteRange(0, cli.cursorPos)
+}
+
+// delete the word before cursor
+func (cli *GoCli) killPrevWord() {
+	cli.deleteRange(cli.getPrevWordPos(), cli.cursorPos)
+}
+
+// clear screen and redraw line on top
+func (cli *GoCli) clearScreen() {
+	libutil.FprintClearScreen(cli.term)
+	cli.redrawLine()
 }
 
 func (cli *GoCli) printHelp() {
-	commandElemSlice := cli.getCommandElemList(cli.getArgs()...)
+	cli.termPrintf("\n")
 
-	fmt.Printf("\n")
+	// help of pipe stages
+	if _, stages := splitPipe(cli.getArgs()); len(stages) > 0 {
+		for _, pipeHelp := range pipeHelpSlice {
+			cli.termPrintf("%*s   \"%s\"\n", 34, pipeHelp[0], pipeHelp[1])
+		}
+		cli.printLine()
+		return
+	}
+
+	commandElemSlice := cli.getModeCommandElemList(cli.getArgs())
 
 	helpStringSlice := make([]string, 0)
 	helpStringMaxLength := 0
 	for _, commandElem := range commandElemSlice {
-		helpString := libutil.GetRegexHelpString(commandElem.Regex)
+		helpString := commandElem.getHelpString()
 		// compare with the longest one
 		if helpStringMaxLength < len(helpString) {
 			helpStringMaxLength = len(helpString)
@@ -106,19 +189,136 @@ func (cli *GoCli) printHelp() {
 
 	for i, helpString := range helpStringSlice {
 		if commandElemSlice[i].Desc != "" {
-			fmt.Printf("%*s   \"%s\"\n", helpStringMaxLength+1, helpString, commandElemSlice[i].Desc)
+			cli.termPrintf("%*s   \"%s\"\n", helpStringMaxLength+1, helpString, commandElemSlice[i].Desc)
 		} else {
-			fmt.Printf("%*s\n", helpStringMaxLength+1, helpString)
+			cli.termPrintf("%*s\n", helpStringMaxLength+1, helpString)
+		}
+	}
+	cli.printLine()
+
+}
+
+// get candidates of command elem. candidates of completers are cached
+// until a command runs, so tab does not query server each time
+func (cli *GoCli) getCachedCandidates(commandElem *CommandElem, args []string) []string {
+	if commandElem.Completer == nil {
+		return commandElem.getCandidates(args)
+	}
+
+	key := strings.Join(args, " ") + "\x00" + commandElem.Regex + "\x00" + commandElem.Desc
+	if candidates, ok := cli.completerCache[key]; ok {
+		return candidates
+	}
+
+	candidates := commandElem.getCandidates(args)
+	cli.completerCache[key] = candidates
+	return candidates
+}
+
+// get candidates of the last arg from completers of command elem merged with
+// keywords of the same node. nil if there is no candidate of completers
+func (cli *GoCli) getCompleterCandidates(args []string, prefix string) []string {
+	if len(args) > 0 && cli.getCommandElemByExactMatch(args...) == nil {
+		return nil
+	}
+
+	candidateSlice := make([]string, 0)
+	keywordSlice := make([]string, 0)
+	for _, commandElem := range cli.getCommandElemList(args...) {
+		if commandElem.isKeyword() && commandElem.Completer == nil {
+			if strings.HasPrefix(commandElem.Regex, prefix) {
+				keywordSlice = append(keywordSlice, commandElem.Regex)
+			}
+			continue
+		}
+
+		for _, candidate := range cli.getCachedCandidates(commandElem, args) {
+			if strings.HasPrefix(candidate, prefix) {
+				candidateSlice = append(candidateSlice, candidate)
+			}
+		}
+	}
+
+	// keywords only are completed by help of command elems
+	if len(candidateSlice) == 0 {
+		return nil
+	}
+
+	candidateSlice = append(candidateSlice, keywordSlice...)
+	sort.Strings(candidateSlice)
+
+	// remove duplicates of sorted candidates
+	uniqueSlice := make([]string, 0)
+	for i, candidate := range candidateSlice {
+		if i == 0 || candidate != candidateSlice[i-1] {
+			uniqueSlice = append(uniqueSlice, candidate)
 		}
 	}
-	fmt.Printf("# %s", cli.buf)
 
+	return uniqueSlice
+}
+
+// get common prefix of strings
+func getCommonPrefix(strSlice []string) string {
+	if len(strSlice) == 0 {
+		return ""
+	}
+
+	prefix := strSlice[0]
+	for _, str := range strSlice[1:] {
+		for !strings.HasPrefix(str, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	return prefix
+}
+
+// tab completion by completers. return false if there is no candidate
+func (cli *GoCli) completerCompletion() bool {
+	args := cli.getArgs()
+	prefix := ""
+	if !cli.getIsLastCharEmpty() {
+		prefix = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+
+	candidateSlice := cli.getCompleterCandidates(cli.getModeArgs(args), prefix)
+	if len(candidateSlice) == 0 {
+		return false
+	}
+
+	completion := getCommonPrefix(candidateSlice)
+	if len(candidateSlice) == 1 {
+		completion += " "
+	} else {
+		cli.termPrintf("\n")
+		for _, candidate := range candidateSlice {
+			cli.termPrintf(" %s", candidate)
+		}
+		cli.termPrintf("\n")
+	}
+
+	line := strings.Join(args, " ")
+	if len(args) > 0 {
+		line += " "
+	}
+	cli.setBuf(line + completion)
+
+	libutil.FprintClearLine(cli.term)
+	cli.printLine()
+
+	return true
 }
 
 // tab completion
 func (cli *GoCli) tabCompletion() {
+	if cli.completerCompletion() {
+		return
+	}
+
 	args := cli.getArgs()
-	commandElemSlice := cli.getCommandElemList(cli.getArgs()...)
+	commandElemSlice := cli.getModeCommandElemList(args)
 	// case 1: no match							eg. # history eee
 	// case 2: incomplete sentece.				eg. # history cle
 	// case 3: complete sentece in regex. 		eg. # histroy 10
@@ -136,8 +336,8 @@ func (cli *GoCli) tabCompletion() {
 		cli.logger.Info("one match")
 
 		// clear line and auto complete to cli buf
-		libutil.ClearLine()                                     // clear line to rewrite cli buf on stdout
-		cli.buf = []byte(strings.Join(args[:len(args)-1], " ")) // join all args to cli buf
+		libutil.FprintClearLine(cli.term)                       // clear line to rewrite cli buf on terminal
+		cli.buf = []rune(strings.Join(args[:len(args)-1], " ")) // join all args to cli buf
 
 		cli.logger.Info("len=%d, args=%v, subargs=%v, arg=%s, regex=%s",
 			len(args), args, args[:len(args)-1], lastArg, commandElemSlice[0].Regex)
@@ -147,40 +347,39 @@ func (cli *GoCli) tabCompletion() {
 		}
 
 		// case 2: exact string completion
-		if strings.HasPrefix(commandElemSlice[0].Regex, lastArg) {
+		if commandElemSlice[0].isKeyword() && strings.HasPrefix(commandElemSlice[0].Regex, lastArg) {
 			cli.logger.Info("incomplete sentence")
 
-			cli.buf = append(cli.buf, []byte(commandElemSlice[0].Regex)...) // the Regex is the exact string
+			cli.buf = append(cli.buf, []rune(commandElemSlice[0].Regex)...) // the Regex is the exact string
 		} else { // case 3: regex completion
 			cli.logger.Info("complete sentence in regex")
 
-			cli.buf = append(cli.buf, []byte(lastArg)...)
+			cli.buf = append(cli.buf, []rune(lastArg)...)
 		}
 		cli.buf = append(cli.buf, ' ')
-		fmt.Printf("# %s", string(cli.buf))
+		cli.printLine()
 	} else { // case 4: already complete sentence
 		cli.logger.Info("multi %d", len(commandElemSlice))
 
 		if len(commandElemSlice) == 1 { // only one match
 			cli.logger.Info("only one match")
-			if libutil.GetRegexHelpString(commandElemSlice[0].Regex) == commandElemSlice[0].Regex {
+			if commandElemSlice[0].isKeyword() {
 				cli.logger.Info("exact string match %s", commandElemSlice[0].Regex)
-				cli.buf = append(cli.buf, []byte(commandElemSlice[0].Regex)...) // the Regex is the exact string
+				cli.buf = append(cli.buf, []rune(commandElemSlice[0].Regex)...) // the Regex is the exact string
 				cli.buf = append(cli.buf, ' ')
 			} else {
 				cli.logger.Info("regex match")
-				fmt.Printf("\n")
-				helpString := libutil.GetRegexHelpString(commandElemSlice[0].Regex)
-				fmt.Printf(" %s", helpString)
+				cli.termPrintf("\n")
+				cli.termPrintf(" %s", commandElemSlice[0].getHelpString())
 			}
 		} else { // multi match then print one line help
 			cli.logger.Info("multi match")
-			fmt.Printf("\n")
+			cli.termPrintf("\n")
 
 			helpStringSlice := make([]string, 0)
 			helpStringMaxLength := 0
 			for _, commandElem := range commandElemSlice {
-				helpString := libutil.GetRegexHelpString(commandElem.Regex)
+				helpString := commandElem.getHelpString()
 				// compare with the longest one
 				if helpStringMaxLength < len(helpString) {
 					helpStringMaxLength = len(helpString)
@@ -189,10 +388,11 @@ func (cli *GoCli) tabCompletion() {
 			}
 
 			for _, helphelpString := range helpStringSlice {
-				fmt.Printf("%*s", helpStringMaxLength+1, helphelpString)
+				cli.termPrintf("%*s", helpStringMaxLength+1, helphelpString)
 			}
 		}
-		fmt.Printf("\n# %s", cli.buf)
+		cli.termPrintf("\n")
+		cli.printLine()
 	}
 
 	cli.cursorPos = cli.getBufLen()
@@ -200,37 +400,90 @@ func (cli *GoCli) tabCompletion() {
 
 func (cli *GoCli) runCommand() {
 	line := cli.getLineString()
-	args := cli.getArgs()
 
-	fmt.Printf("\n")
+	cli.termPrintf("\n")
 
 	// if line is empty, just return
 	if len(line) == 0 {
-		cli.buf = make([]byte, 0)
-		fmt.Printf("# ")
+		cli.buf = make([]rune, 0)
+		cli.termPrintf("%s", cli.getPrompt())
 		return
 	}
 
-	// update history
-	if cli.getHistoryLen() == 0 || cli.historySlice[cli.getHistoryLen()-1] != line {
-		cli.historySlice = append(cli.historySlice, line)
+	// expand !! and !N to the line in history
+	line, err := cli.expandHistory(line)
+	if err != nil {
+		cli.termPrintf("%v\n%s", err, cli.getPrompt())
+		cli.buf = make([]rune, 0)
+		cli.cursorPos = 0
+		return
+	}
+	if line != cli.getLineString() {
+		cli.termPrintf("%s\n", line)
 	}
-	cli.historyPos = cli.getHistoryLen()
 
-	// find command function. if not found, print command not found
-	commandElem := cli.getCommandElemByExactMatch(args...)
-	if commandElem != nil && commandElem.Func != nil {
-		commandElem.Func(args)
-	} else {
-		fmt.Printf("command \"%s\" does not exist\n", line)
+	// update history
+	cli.updateHistory(line)
+
+	// completed resources may be changed by the command
+	cli.completerCache = make(map[string][]string)
+
+	if err := cli.execLineWithPager(line); err != nil {
+		var caretErr *caretError
+		if errors.As(err, &caretErr) {
+			cli.termPrintf("%s\n", caretErr.format(cli.getPrompt()))
+		} else {
+			cli.termPrintf("%v\n", err)
+		}
 	}
-	fmt.Printf("# ")
+	cli.termPrintf("%s", cli.getPrompt())
 
 	// clear cli buf
-	cli.buf = make([]byte, 0)
+	cli.buf = make([]rune, 0)
 	cli.cursorPos = 0
 }
 
+// find command function by line and run it
+func (cli *GoCli) execLine(line string) error {
+	args := libutil.RemoveEmptyString(strings.Split(line, " "))
+
+	// pipe stages. eg. # route show | include 10.0. | json
+	args, stages := splitPipe(args)
+
+	// find command function and validate args. args are prefixed by mode
+	commandElem, modeArgs, argErr := cli.matchModeArgs(args)
+	if argErr != nil {
+		return newCaretError(line, args, argErr.index, argErr.err)
+	}
+
+	// pipe stages are part of line. eg. # watch link show | include eth
+	if len(stages) > 0 && commandElem.isRest() {
+		modeArgs = append(modeArgs[:len(modeArgs)-len(args)],
+			libutil.RemoveEmptyString(strings.Split(line, " "))...)
+		stages = nil
+	}
+
+	if len(stages) > 0 {
+		if commandElem.Func == nil {
+			return fmt.Errorf("mode \"%s\" can not be piped", commandElem.Mode)
+		}
+		return cli.runPipe(commandElem, modeArgs, stages)
+	}
+
+	if commandElem.Func != nil {
+		if err := commandElem.Func(modeArgs); err != nil {
+			return err
+		}
+	}
+
+	// enter mode of command. eg. # domain web01
+	if commandElem.Mode != "" {
+		cli.enterMode(commandElem.Mode, modeArgs)
+	}
+
+	return nil
+}
+
 // select history by direction
 func (cli *GoCli) selectHistory(direction int) {
 	if cli.getHistoryLen() == 0 {
@@ -238,7 +491,7 @@ func (cli *GoCli) selectHistory(direction int) {
 	}
 
 	// clear line and auto complete to cli buf
-	libutil.ClearLine() // clear line to rewrite cli buf on stdout
+	libutil.FprintClearLine(cli.term) // clear line to rewrite cli buf on terminal
 
 	// update history pos
 	cli.historyPos += direction
@@ -249,9 +502,8 @@ func (cli *GoCli) selectHistory(direction int) {
 	}
 
 	// update cli buf
-	cli.buf = []byte(cli.historySlice[cli.historyPos])
-	cli.cursorPos = cli.getBufLen()
-	fmt.Printf("# %s", cli.buf)
+	cli.setBuf(cli.historySlice[cli.historyPos])
+	cli.printLine()
 }
 
 func (cli *GoCli) getCommandElemByExactMatch(args ...string) *CommandElem {
@@ -259,51 +511,119 @@ func (cli *GoCli) getCommandElemByExactMatch(args ...string) *CommandElem {
 	commandMap := cli.commandMap
 	var commandElem *CommandElem = nil
 	for _, arg := range args {
-		commandElem = nil
-		for regex := range commandMap {
-			match, _ := regexp.MatchString("\\b"+regex+"\\b", arg)
-
-			cli.logger.Info("regex=%s, arg=%s", "\\b"+regex+"\\b", arg)
-			if match {
-				cli.logger.Info("match regex=%s, arg=%s", regex, arg)
-				commandElem = commandMap[regex]
-				commandMap = commandElem.commandMap
-				break
-			}
-		}
-
+		commandElem = findCommandElem(commandMap, arg)
 		if commandElem == nil {
 			return nil
 		}
+
+		// the rest of args are params or line
+		if len(commandElem.Params) > 0 || commandElem.isRest() {
+			return commandElem
+		}
+		commandMap = commandElem.commandMap
 	}
 
 	return commandElem
 }
 
+// match args to command elem and validate args by kinds.
+// error has index of the offending arg
+func (cli *GoCli) matchArgs(args []string) (*CommandElem, *argError) {
+	commandMap := cli.commandMap
+	var commandElem *CommandElem = nil
+	for i, arg := range args {
+		commandElem = findCommandElem(commandMap, arg)
+		if commandElem == nil {
+			return nil, &argError{index: i, err: fmt.Errorf("unknown argument \"%s\", expected %s",
+				arg, getExpectedString(commandMap))}
+		}
+
+		if !commandElem.isKeyword() {
+			if err := commandElem.Kind.validate(arg); err != nil {
+				return nil, &argError{index: i, err: err}
+			}
+		}
+
+		// the rest of args are line
+		if commandElem.isRest() {
+			return commandElem, nil
+		}
+
+		// the rest of args are params
+		if len(commandElem.Params) > 0 {
+			if _, err := commandElem.parseParams(args[i+1:]); err != nil {
+				err.index += i + 1
+				return nil, err
+			}
+			return commandElem, nil
+		}
+		commandMap = commandElem.commandMap
+	}
+
+	if commandElem == nil || (commandElem.Func == nil && commandElem.Mode == "") {
+		return nil, &argError{index: len(args), err: fmt.Errorf("incomplete command, expected %s",
+			getExpectedString(commandMap))}
+	}
+
+	return commandElem, nil
+}
+
+// find command elem matching arg in command map. keyword is matched before kind
+func findCommandElem(commandMap map[string]*CommandElem, arg string) *CommandElem {
+	if commandElem := commandMap[arg]; commandElem != nil && commandElem.isKeyword() {
+		return commandElem
+	}
+
+	regexSlice := make([]string, 0)
+	for regex := range commandMap {
+		regexSlice = append(regexSlice, regex)
+	}
+	sort.Strings(regexSlice)
+
+	for _, regex := range regexSlice {
+		commandElem := commandMap[regex]
+		if !commandElem.isKeyword() && commandElem.Kind.match(arg) {
+			return commandElem
+		}
+	}
+
+	return nil
+}
+
+// get help strings of command map joined for error message
+func getExpectedString(commandMap map[string]*CommandElem) string {
+	helpStringSlice := make([]string, 0)
+	for _, commandElem := range commandMap {
+		helpStringSlice = append(helpStringSlice, commandElem.getHelpString())
+	}
+	sort.Strings(helpStringSlice)
+
+	return strings.Join(helpStringSlice, ", ")
+}
+
 // get matched command elem list
 func (cli *GoCli) getCommandElemList(args ...string) []*CommandElem {
 	commandElemSlice := make([]*CommandElem, 0)
 	commandMap := cli.commandMap
 	var commandElem *CommandElem = nil
 
-	if args != nil {
-		for _, arg := range args {
+	if len(args) > 0 {
+		for i, arg := range args {
 			cli.logger.Info("arg=%s", arg)
-			commandElem = nil
-			for regex := range commandMap {
-				cli.logger.Info("regex=%s", regex)
-				match, _ := regexp.MatchString(regex, arg)
-				if match {
-					if libutil.GetRegexHelpString(regex) == regex && arg != regex { // exact string
-						continue
-					}
+			commandElem = findCommandElem(commandMap, arg)
+			if commandElem != nil {
+				cli.logger.Info("match arg=%s, regex=%s", arg, commandElem.Regex)
+				commandMap = commandElem.commandMap
+			}
 
-					cli.logger.Info("match arg=%s, regex=%s", arg, regex)
-					commandElem = commandMap[regex]
-					commandMap = commandElem.commandMap
-					cli.logger.Info("commandElem=%v, commandMap=%v", commandElem, commandMap)
-					break
-				}
+			// the rest of args are params
+			if commandElem != nil && len(commandElem.Params) > 0 {
+				return commandElem.getParamElemList(args[i+1:])
+			}
+
+			// the rest of args are line
+			if commandElem != nil && commandElem.isRest() {
+				return []*CommandElem{commandElem}
 			}
 
 			if commandElem == nil {
@@ -343,11 +663,12 @@ func (cli *GoCli) defaultCommand() {
 	// show history
 	cli.AddCommandElem(
 		NewCommandElemWithoutFunc("history", ""),
-		NewCommandElem("show", "show history", func(args []string) {
-			fmt.Printf("\n")
+		NewCommandElem("show", "show history", func(args []string) error {
+			cli.Printf("\n")
 			for index, each := range cli.historySlice {
-				fmt.Printf(" %d %s\n", index, each)
+				cli.Printf(" %d %s\n", index, each)
 			}
+			return nil
 		}))
 
 	// show history	last n
@@ -355,39 +676,55 @@ func (cli *GoCli) defaultCommand() {
 		NewCommandElemWithoutFunc("history", "show history"),
 		NewCommandElemWithoutFunc("show", ""),
 		NewCommandElemWithoutFunc("last", "show history last n"),
-		NewCommandElem(libutil.NumberRegex, "last n", func(args []string) {
-			fmt.Printf("\n")
+		NewArgElem(KindInt(1, math.MaxInt32), "last n", func(args []string) error {
+			cli.Printf("\n")
+			value, _ := strconv.Atoi(args[3])
 			for index, each := range cli.historySlice {
-				if value, _ := strconv.Atoi(args[3]); value <= index {
-					break
+				if index < cli.getHistoryLen()-value {
+					continue
 				}
-				fmt.Printf(" %d %s\n", index, each)
+				cli.Printf(" %d %s\n", index, each)
 			}
+			return nil
 		}))
 
 	// clear history
 	cli.AddCommandElem(
 		NewCommandElemWithoutFunc("history", ""),
-		NewCommandElem("clear", "clear history", func(args []string) {
-			fmt.Printf("\n")
+		NewCommandElem("clear", "clear history", func(args []string) error {
+			cli.Printf("\n")
 			cli.historySlice = make([]string, 0)
+			cli.historyPos = 0
+			return cli.saveHistory()
 		}))
 
+	// set output format
+	cli.AddCommandElem(
+		NewCommandElemWithoutFunc("set", ""),
+		NewCommandElemWithoutFunc("output", "set output format of show commands"),
+		NewArgElem(KindEnum(libutil.OutputTable, libutil.OutputWide, libutil.OutputJson, libutil.OutputYaml, libutil.OutputCsv),
+			"output format", func(args []string) error {
+				cli.outputFormat = args[2]
+				return nil
+			}))
+
 	// clear screen
 	cli.AddCommandElem(
-		NewCommandElem("clear", "clear screen", func(args []string) {
-			libutil.ClearScreen()
+		NewCommandElem("clear", "clear screen", func(args []string) error {
+			libutil.FprintClearScreen(cli.term)
+			return nil
 		}))
 
 	// quit
 	cli.AddCommandElem(
-		NewCommandElem("quit", "quit", func(args []string) {
+		NewCommandElem("quit", "quit", func(args []string) error {
 			cli.isRunning = false
+			return nil
 		}))
 
 	// show help
 	cli.AddCommandElem(
-		NewCommandElem("help", "show help", func(args []string) {
+		NewCommandElem("help", "show help", func(args []string) error {
 			commandElemSlice := make([]*CommandElem, 0)
 			maxLength := 0
 			for key := range cli.commandMap {
@@ -400,7 +737,8 @@ func (cli *GoCli) defaultCommand() {
 			sort.Slice(commandElemSlice, func(i, j int) bool { return commandElemSlice[i].Regex < commandElemSlice[j].Regex })
 
 			for _, commandElem := range commandElemSlice {
-				fmt.Printf("%-*s %s\n", maxLength+1, commandElem.Regex, commandElem.Desc)
+				cli.Printf("%-*s %s\n", maxLength+1, commandElem.Regex, commandElem.Desc)
 			}
+			return nil
 		}))
 }
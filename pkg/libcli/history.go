@@ -0,0 +1,192 @@
+package libcli
+
+import (
+	"fmt"
+	"go-cli/pkg/libutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/eiannone/keyboard"
+)
+
+const defaultHistorySize = 1000
+
+// load history from history file
+func (cli *GoCli) loadHistory() error {
+	if cli.HistoryPath == "" || !libutil.IsExist(cli.HistoryPath) {
+		return nil
+	}
+
+	data, err := libutil.ReadFile(cli.HistoryPath)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		cli.appendHistory(line)
+	}
+	cli.historyPos = cli.getHistoryLen()
+
+	return nil
+}
+
+// save history to history file
+func (cli *GoCli) saveHistory() error {
+	if cli.HistoryPath == "" {
+		return nil
+	}
+
+	data := strings.Join(cli.historySlice, "\n")
+	if len(data) > 0 {
+		data += "\n"
+	}
+
+	return os.WriteFile(cli.HistoryPath, []byte(data), 0600)
+}
+
+// append line to history. the same line is moved to the end and the oldest
+// lines are dropped when history exceeds history size
+func (cli *GoCli) appendHistory(line string) {
+	for i, each := range cli.historySlice {
+		if each == line {
+			cli.historySlice = append(cli.historySlice[:i], cli.historySlice[i+1:]...)
+			break
+		}
+	}
+	cli.historySlice = append(cli.historySlice, line)
+
+	historySize := cli.HistorySize
+	if historySize <= 0 {
+		historySize = defaultHistorySize
+	}
+	if cli.getHistoryLen() > historySize {
+		cli.historySlice = cli.historySlice[cli.getHistoryLen()-historySize:]
+	}
+}
+
+// update history by line and save it
+func (cli *GoCli) updateHistory(line string) {
+	cli.appendHistory(line)
+	cli.historyPos = cli.getHistoryLen()
+
+	if err := cli.saveHistory(); err != nil {
+		cli.logger.Warn("failed to save history: %v", err)
+	}
+}
+
+// expand !! and !N to the line in history
+func (cli *GoCli) expandHistory(line string) (string, error) {
+	if !strings.HasPrefix(line, "!") {
+		return line, nil
+	}
+
+	if cli.getHistoryLen() == 0 {
+		return "", fmt.Errorf("%s: event not found", line)
+	}
+
+	if line == "!!" {
+		return cli.historySlice[cli.getHistoryLen()-1], nil
+	}
+
+	index, err := strconv.Atoi(line[1:])
+	if err != nil || index < 0 || index >= cli.getHistoryLen() {
+		return "", fmt.Errorf("%s: event not found", line)
+	}
+
+	return cli.historySlice[index], nil
+}
+
+// find history index matching query, searching backward from start
+func (cli *GoCli) findHistory(query string, start int) int {
+	if start >= cli.getHistoryLen() {
+		start = cli.getHistoryLen() - 1
+	}
+
+	for i := start; i >= 0; i-- {
+		if strings.Contains(cli.historySlice[i], query) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// print reverse search prompt with query and matched history
+func (cli *GoCli) printSearch() {
+	match := ""
+	if cli.searchPos >= 0 && cli.searchPos < cli.getHistoryLen() {
+		match = cli.historySlice[cli.searchPos]
+	}
+
+	libutil.FprintClearLine(cli.term)
+	cli.termPrintf("(reverse-i-search)'%s': %s", cli.searchQuery, match)
+}
+
+// start reverse search or find next older match
+func (cli *GoCli) reverseSearch() {
+	if !cli.isSearching {
+		cli.isSearching = true
+		cli.searchQuery = ""
+		cli.searchPos = cli.findHistory("", cli.getHistoryLen()-1)
+	} else if cli.searchPos > 0 {
+		if pos := cli.findHistory(cli.searchQuery, cli.searchPos-1); pos >= 0 {
+			cli.searchPos = pos
+		}
+	}
+
+	cli.printSearch()
+}
+
+// update query of reverse search
+func (cli *GoCli) updateSearch(query string) {
+	cli.searchQuery = query
+	cli.searchPos = cli.findHistory(cli.searchQuery, cli.getHistoryLen()-1)
+
+	cli.printSearch()
+}
+
+// finish reverse search. matched history is put on cli buf if accept is true
+func (cli *GoCli) finishSearch(accept bool) {
+	cli.isSearching = false
+
+	if accept && cli.searchPos >= 0 && cli.searchPos < cli.getHistoryLen() {
+		cli.setBuf(cli.historySlice[cli.searchPos])
+		cli.historyPos = cli.searchPos
+	}
+	cli.cursorPos = cli.getBufLen()
+
+	libutil.FprintClearLine(cli.term)
+	cli.printLine()
+}
+
+// handle key input while reverse searching. unhandled key finishes search
+// and returns false to be dispatched as normal key input
+func (cli *GoCli) searchKey(char rune, key keyboard.Key) bool {
+	switch {
+	case char != 0:
+		cli.updateSearch(cli.searchQuery + string(char))
+	case key == keyboard.KeySpace:
+		cli.updateSearch(cli.searchQuery + " ")
+	case key == keyboard.KeyBackspace2 || key == keyboard.KeyBackspace:
+		if query := []rune(cli.searchQuery); len(query) > 0 {
+			cli.updateSearch(string(query[:len(query)-1]))
+		}
+	case key == keyboard.KeyCtrlR:
+		cli.reverseSearch()
+	case key == keyboard.KeyEsc || key == keyboard.KeyCtrlG:
+		cli.finishSearch(false)
+	case key == keyboard.KeyEnter:
+		cli.finishSearch(true)
+		cli.runCommand()
+	default:
+		cli.finishSearch(true)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,358 @@
+package libcli
+
+import (
+	"fmt"
+	"go-cli/pkg/libutil"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kind of argument. Regex matches the shape of arg in command tree and
+// Validate checks the value before dispatch
+type Kind struct {
+	// help string of kind. eg. INT(1-4094)
+	Name     string
+	Regex    string
+	Validate func(arg string) error
+	// candidates of enum kind
+	values []string
+	// matches the rest of args
+	rest bool
+}
+
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_\-\.]+$`)
+
+var (
+	KindName = &Kind{
+		Name:  "NAME",
+		Regex: `^[^\s|]+$`,
+		Validate: func(arg string) error {
+			if !nameRegexp.MatchString(arg) {
+				return fmt.Errorf("only number, letter, underscore, hyphen and dot are allowed")
+			}
+			return nil
+		},
+	}
+	KindIp = &Kind{
+		Name:  "IP(x.x.x.x|x:x::x)",
+		Regex: `^[0-9a-fA-F:.]+$`,
+		Validate: func(arg string) error {
+			if net.ParseIP(arg) == nil {
+				return fmt.Errorf("not an ipv4 or ipv6 address")
+			}
+			return nil
+		},
+	}
+	KindCidr = &Kind{
+		Name:  "CIDR(IP/MASK)",
+		Regex: `^[0-9a-fA-F:.]+/[0-9]+$`,
+		Validate: func(arg string) error {
+			if _, _, err := net.ParseCIDR(arg); err != nil {
+				return fmt.Errorf("not an ipv4 or ipv6 address with mask")
+			}
+			return nil
+		},
+	}
+	// ipv4 only. eg. ip of vm network
+	KindIpv4 = &Kind{
+		Name:  "IP(x.x.x.x)",
+		Regex: `^[0-9.]+$`,
+		Validate: func(arg string) error {
+			if ip := net.ParseIP(arg); ip == nil || ip.To4() == nil {
+				return fmt.Errorf("not an ipv4 address")
+			}
+			return nil
+		},
+	}
+	KindCidrv4 = &Kind{
+		Name:  "CIDR(x.x.x.x/MASK)",
+		Regex: `^[0-9.]+/[0-9]+$`,
+		Validate: func(arg string) error {
+			ip, _, err := net.ParseCIDR(arg)
+			if err != nil || ip.To4() == nil {
+				return fmt.Errorf("not an ipv4 address with mask")
+			}
+			return nil
+		},
+	}
+	// nexthop ip with optional device, or nexthops of multipath. eg. 10.0.0.1, fe80::1@eth0,fe80::2@eth1
+	KindNextHop = &Kind{
+		Name:     "NEXTHOP(IP[@DEV][,IP[@DEV]...])",
+		Regex:    `^[0-9a-fA-F:.]+(@[^\s|,@]+)?(,[0-9a-fA-F:.]+(@[^\s|,@]+)?)*$`,
+		Validate: validateNextHop,
+	}
+	KindMac = &Kind{
+		Name:  "MAC(XX:XX:XX:XX:XX:XX)",
+		Regex: `^[0-9a-fA-F:\-]+$`,
+		Validate: func(arg string) error {
+			if mac, err := net.ParseMAC(arg); err != nil || len(mac) != 6 {
+				return fmt.Errorf("not a 48 bit mac address")
+			}
+			return nil
+		},
+	}
+	KindSize = &Kind{
+		Name:  "SIZE(k|m|g)",
+		Regex: `^[0-9]+[a-zA-Z]?$`,
+		Validate: func(arg string) error {
+			if _, err := libutil.ConvertSizeToBytes(arg); err != nil {
+				return fmt.Errorf("unit must be one of k, m and g")
+			}
+			return nil
+		},
+	}
+	KindPortRange = &Kind{
+		Name:     "PORT_RANGE(1-65535)",
+		Regex:    `^[0-9]+-[0-9]+$`,
+		Validate: validatePortRange,
+	}
+	KindTable = &Kind{
+		Name:  "TABLE(NUMBER|local|main|default)",
+		Regex: `^([0-9]+|local|main|default)$`,
+		Validate: func(arg string) error {
+			if _, err := strconv.Atoi(arg); err != nil {
+				return nil
+			}
+			return KindInt(0, math.MaxInt32).Validate(arg)
+		},
+	}
+	KindProto    = KindEnum("tcp", "udp", "icmp", "icmpv6")
+	KindFamily   = KindEnum("inet", "inet6")
+	KindDuration = &Kind{
+		Name:  "DURATION(s|m|h)",
+		Regex: `^([0-9]+[smh])+$`,
+		Validate: func(arg string) error {
+			if duration, err := time.ParseDuration(arg); err != nil || duration <= 0 {
+				return fmt.Errorf("not a positive duration. eg. 30s, 5m, 1h")
+			}
+			return nil
+		},
+	}
+	// duration before now or local time. eg. 1h, 2006-01-02, 2006-01-02T15:04:05
+	KindTime = &Kind{
+		Name:  "TIME(DURATION|DATE[THH:MM:SS])",
+		Regex: `^(([0-9]+[smh])+|[0-9]{4}-[0-9]{2}-[0-9]{2}(T[0-9]{2}:[0-9]{2}:[0-9]{2})?)$`,
+		Validate: func(arg string) error {
+			_, err := libutil.ParseSince(arg, time.Now())
+			return err
+		},
+	}
+	// the rest of args. eg. # alias web domain create name $1
+	KindLine = &Kind{
+		Name:  "LINE",
+		Regex: `^.+$`,
+		rest:  true,
+	}
+	// file path which is not checked
+	KindPath = &Kind{
+		Name:  "PATH",
+		Regex: `^[^\s|]+$`,
+	}
+	// file path which must exist
+	KindExistPath = &Kind{
+		Name:  "PATH(must exist)",
+		Regex: `^[^\s|]+$`,
+		Validate: func(arg string) error {
+			if !libutil.IsExist(arg) {
+				return fmt.Errorf("no such file or directory")
+			}
+			return nil
+		},
+	}
+)
+
+// integer kind in range [min, max]
+func KindInt(min int, max int) *Kind {
+	return &Kind{
+		Name:  fmt.Sprintf("INT(%d-%d)", min, max),
+		Regex: `^-?[0-9]+$`,
+		Validate: func(arg string) error {
+			value, err := strconv.Atoi(arg)
+			if err != nil || value < min || value > max {
+				return fmt.Errorf("out of range %d-%d", min, max)
+			}
+			return nil
+		},
+	}
+}
+
+// enum kind of values
+func KindEnum(values ...string) *Kind {
+	quoted := make([]string, 0)
+	for _, value := range values {
+		quoted = append(quoted, regexp.QuoteMeta(value))
+	}
+
+	return &Kind{
+		Name:   fmt.Sprintf("(%s)", strings.Join(values, "|")),
+		Regex:  fmt.Sprintf("^(%s)$", strings.Join(quoted, "|")),
+		values: values,
+	}
+}
+
+// validate port range. eg. 1000-2000
+func validatePortRange(arg string) error {
+	start, end, _ := strings.Cut(arg, "-")
+	startPort, err := strconv.Atoi(start)
+	if err != nil || startPort < 1 || startPort > 65535 {
+		return fmt.Errorf("start port is out of range 1-65535")
+	}
+
+	endPort, err := strconv.Atoi(end)
+	if err != nil || endPort < 1 || endPort > 65535 {
+		return fmt.Errorf("end port is out of range 1-65535")
+	}
+
+	if startPort > endPort {
+		return fmt.Errorf("start port is greater than end port")
+	}
+
+	return nil
+}
+
+// validate nexthops separated by comma. each is ip with optional device. eg. 10.0.0.1@eth0
+func validateNextHop(arg string) error {
+	for _, nextHop := range strings.Split(arg, ",") {
+		ip, device, hasDevice := strings.Cut(nextHop, "@")
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("\"%s\" is not an ipv4 or ipv6 address", ip)
+		}
+
+		if hasDevice && !nameRegexp.MatchString(device) {
+			return fmt.Errorf("device \"%s\" has characters other than number, letter, underscore, hyphen and dot", device)
+		}
+	}
+
+	return nil
+}
+
+// match shape of arg by regex of kind
+func (kind *Kind) match(arg string) bool {
+	match, _ := regexp.MatchString(kind.Regex, arg)
+	return match
+}
+
+// validate arg by kind. error contains name of kind
+func (kind *Kind) validate(arg string) error {
+	if !kind.match(arg) {
+		return fmt.Errorf("invalid value \"%s\", expected %s", arg, kind.Name)
+	}
+
+	if kind.Validate == nil {
+		return nil
+	}
+
+	if err := kind.Validate(arg); err != nil {
+		return fmt.Errorf("invalid %s \"%s\": %v", kind.Name, arg, err)
+	}
+
+	return nil
+}
+
+// is keyword which matches the exact string
+func (elem *CommandElem) isKeyword() bool {
+	return elem.Kind == nil
+}
+
+// is argument taking the rest of args
+func (elem *CommandElem) isRest() bool {
+	return elem.Kind != nil && elem.Kind.rest
+}
+
+// get help string of command elem
+func (elem *CommandElem) getHelpString() string {
+	if elem.isKeyword() {
+		return elem.Regex
+	}
+
+	return elem.Kind.Name
+}
+
+// get candidates for tab completion by completer or values of enum kind
+func (elem *CommandElem) getCandidates(args []string) []string {
+	if elem.Completer != nil {
+		return elem.Completer(args)
+	}
+
+	if elem.Kind != nil {
+		return elem.Kind.values
+	}
+
+	return nil
+}
+
+// command elem of argument kind without func
+func NewArgElemWithoutFunc(kind *Kind, desc string) *CommandElem {
+	return &CommandElem{
+		Regex:      kind.Regex,
+		Desc:       desc,
+		Kind:       kind,
+		commandMap: make(map[string]*CommandElem),
+	}
+}
+
+// command elem of argument kind
+func NewArgElem(kind *Kind, desc string, f func(args []string) error) *CommandElem {
+	return &CommandElem{
+		Regex:      kind.Regex,
+		Desc:       desc,
+		Kind:       kind,
+		Func:       f,
+		commandMap: make(map[string]*CommandElem),
+	}
+}
+
+// error of arg at index of args
+type argError struct {
+	index int
+	err   error
+}
+
+func (e *argError) Error() string {
+	return e.err.Error()
+}
+
+// caret style error pointing at the offending arg in line
+//
+//	route add 10.0.0.0/33 via 10.0.0.1
+//	          ^
+//	invalid CIDR(IP/MASK) "10.0.0.0/33": not an ipv4 address with mask
+type caretError struct {
+	line  string
+	width int
+	err   error
+}
+
+// new caret error pointing at arg of index in line
+func newCaretError(line string, args []string, index int, err error) *caretError {
+	pos := 0
+	for i, arg := range args {
+		pos += strings.Index(line[pos:], arg)
+		if i == index {
+			break
+		}
+		pos += len(arg)
+	}
+
+	width := libutil.RunesWidth([]rune(line[:pos]))
+	// one past the last arg
+	if index >= len(args) {
+		width++
+	}
+
+	return &caretError{line: line, width: width, err: err}
+}
+
+// format caret error with prefix of line
+func (e *caretError) format(prefix string) string {
+	return fmt.Sprintf("%s%s\n%s^\n%v", prefix, e.line,
+		strings.Repeat(" ", libutil.RunesWidth([]rune(prefix))+e.width), e.err)
+}
+
+func (e *caretError) Error() string {
+	return e.format("")
+}
@@ -1,30 +1,81 @@
 package libcli
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"go-cli/pkg/libutil"
+	"io"
+	"os"
+	"strings"
 
 	nblogger "github.com/banaconda/nb-logger"
 	"github.com/eiannone/keyboard"
 )
 
 type CommandElem struct {
-	Regex      string
-	Desc       string
-	Func       func(args []string)
+	Regex string
+	Desc  string
+	Func  func(args []string) error
+	// list candidates of regex for tab completion. args are the preceding args
+	Completer func(args []string) []string
+	// kind of argument. command elem without kind is a keyword
+	Kind *Kind
+	// mode entered by the command. eg. # domain web01 → (domain:web01)#
+	Mode string
+	// keyword value params following the command. ParamFunc is called by Func
+	// with args before params
+	Params     []*Param
+	ParamFunc  func(args []string, params map[string]string) error
 	commandMap map[string]*CommandElem
 }
 
 type GoCli struct {
+	// history file path. history is not saved if empty
+	HistoryPath string
+	// max number of history lines
+	HistorySize int
+	// alias and macro file path. aliases are not saved if empty
+	AliasPath string
+
 	logger       nblogger.Logger
 	isRunning    bool
 	historyPos   int
 	historySlice []string
 	commandMap   map[string]*CommandElem
-	buf          []byte
+	buf          []rune
 	cursorPos    int
+	outputFormat string
+	out          io.Writer
+	// terminal of prompt and line editing
+	term io.Writer
+	keys <-chan keyboard.KeyEvent
+	// number of lines of terminal window. 0 if unknown
+	windowHeight int32
+	// number of lines of pager. 0 disables pager
+	terminalLength int
+
+	// pipe
+	isPiping   bool
+	pipeHeader string
+
+	// reverse search
+	isSearching bool
+	searchQuery string
+	searchPos   int
+
+	// mode
+	modeStack []modeContext
+
+	// candidates of completers by args
+	completerCache map[string][]string
+
+	// alias
+	aliasMap   map[string]*alias
+	aliasDepth int
 }
 
-func NewCommandElem(regex string, desc string, f func(args []string)) *CommandElem {
+func NewCommandElem(regex string, desc string, f func(args []string) error) *CommandElem {
 	return &CommandElem{
 		Regex:      regex,
 		Desc:       desc,
@@ -45,46 +96,156 @@ func (cli *GoCli) Init(logger nblogger.Logger) {
 	cli.commandMap = make(map[string]*CommandElem, 0)
 	cli.logger = logger
 	cli.historySlice = make([]string, 0)
-	cli.buf = make([]byte, 0)
+	cli.buf = make([]rune, 0)
+	cli.outputFormat = libutil.OutputTable
+	cli.out = os.Stdout
+	cli.term = os.Stdout
+	cli.terminalLength = terminalLengthAuto
+	cli.aliasMap = make(map[string]*alias)
+	cli.completerCache = make(map[string][]string)
+
+	if err := cli.loadHistory(); err != nil {
+		cli.logger.Warn("failed to load history: %v", err)
+	}
 
 	cli.defaultCommand()
+	cli.modeCommand()
+	cli.aliasCommand()
+	cli.watchCommand()
+	cli.pagerCommand()
+}
+
+// load aliases after all commands are added, so aliases can not replace them
+func (cli *GoCli) initAlias() {
+	if err := cli.loadAlias(); err != nil {
+		cli.logger.Warn("failed to load alias: %v", err)
+	}
+}
+
+// set completer of command elem
+func (elem *CommandElem) SetCompleter(completer func(args []string) []string) *CommandElem {
+	elem.Completer = completer
+	return elem
 }
 
 func (cli *GoCli) AddCommandElem(commandElemSlice ...*CommandElem) {
 	commandMap := cli.commandMap
 	var commandElem *CommandElem = nil
-	for _, elem := range commandElemSlice {
+	for i, elem := range commandElemSlice {
 		commandElem = commandMap[elem.Regex]
 		if commandElem == nil {
 			commandElem = &CommandElem{
 				Regex:      elem.Regex,
 				Desc:       elem.Desc,
 				Func:       elem.Func,
+				Completer:  elem.Completer,
+				Kind:       elem.Kind,
+				Mode:       elem.Mode,
+				Params:     elem.Params,
+				ParamFunc:  elem.ParamFunc,
 				commandMap: make(map[string]*CommandElem),
 			}
+			if commandElem.ParamFunc != nil {
+				commandElem.Func = commandElem.getParamFunc(i + 1)
+			}
 			commandMap[elem.Regex] = commandElem
+		} else {
+			if commandElem.Func == nil && commandElem.ParamFunc == nil {
+				commandElem.Func = elem.Func
+			}
+			if commandElem.Completer == nil {
+				commandElem.Completer = elem.Completer
+			}
+			if commandElem.Mode == "" {
+				commandElem.Mode = elem.Mode
+			}
 		}
 		commandMap = commandElem.commandMap
 	}
 }
 
+// print command output
+func (cli *GoCli) Printf(format string, a ...any) {
+	fmt.Fprintf(cli.out, format, a...)
+}
+
+// print struct slice by output format of GoCli
+func (cli *GoCli) PrintStructAll(elems any) {
+	isTable := cli.outputFormat == libutil.OutputTable || cli.outputFormat == libutil.OutputWide
+	if !cli.isPiping || !isTable {
+		if err := libutil.FprintStructAll(cli.out, cli.outputFormat, elems); err != nil {
+			cli.logger.Warn("%v", err)
+		}
+		return
+	}
+
+	// keep table header out of pipe filters
+	var sb strings.Builder
+	if err := libutil.FprintStructAll(&sb, cli.outputFormat, elems); err != nil {
+		cli.logger.Warn("%v", err)
+		return
+	}
+
+	header, body, _ := strings.Cut(sb.String(), "\n")
+	if cli.pipeHeader == "" {
+		cli.pipeHeader = header
+	}
+	cli.Printf("%s", body)
+}
+
+// print struct by output format of GoCli
+func (cli *GoCli) PrintStructOne(elem any) {
+	cli.PrintStructAll([]any{elem})
+}
+
 func (cli *GoCli) Run() {
-	if err := keyboard.Open(); err != nil {
-		panic(err)
+	// keyboard of stdin unless terminal is set
+	if cli.keys == nil {
+		if err := keyboard.Open(); err != nil {
+			panic(err)
+		}
+		defer func() {
+			_ = keyboard.Close()
+			cli.keys = nil
+		}()
+
+		keys, err := keyboard.GetKeys(10)
+		if err != nil {
+			panic(err)
+		}
+		cli.keys = keys
 	}
-	defer func() {
-		_ = keyboard.Close()
-	}()
 
 	// default command
 	cli.isRunning = true
+	cli.initAlias()
 
-	fmt.Println("Press ESC to quit")
-	fmt.Printf("# ")
+	cli.termPrintf("Press ESC to quit\n")
+	cli.termPrintf("%s", cli.getPrompt())
 	for cli.isRunning {
-		char, key, err := keyboard.GetKey()
+		char, key, err := cli.getKey()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			panic(err)
+			// unrecognized escape sequence, e.g. unsupported function key
+			cli.logger.Warn("%v", err)
+			continue
+		}
+
+		if cli.isSearching && cli.searchKey(char, key) {
+			continue
+		}
+
+		// alt key combination comes as esc with char
+		if key == keyboard.KeyEsc && char != 0 {
+			switch char {
+			case 'b', 'B':
+				cli.setCursor(cli.getPrevWordPos())
+			case 'f', 'F':
+				cli.setCursor(cli.getNextWordPos())
+			}
+			continue
 		}
 
 		// normal key input
@@ -102,22 +263,80 @@ func (cli *GoCli) Run() {
 			cli.isRunning = false
 		case keyboard.KeySpace:
 			cli.inputChar(' ')
-		case keyboard.KeyBackspace2:
+		case keyboard.KeyBackspace, keyboard.KeyBackspace2:
 			cli.backspace()
+		case keyboard.KeyDelete:
+			cli.deleteChar()
+		case keyboard.KeyCtrlA, keyboard.KeyHome:
+			cli.setCursor(0)
+		case keyboard.KeyCtrlE, keyboard.KeyEnd:
+			cli.setCursor(cli.getBufLen())
+		case keyboard.KeyCtrlK:
+			cli.killLineEnd()
+		case keyboard.KeyCtrlU:
+			cli.killLineStart()
+		case keyboard.KeyCtrlW:
+			cli.killPrevWord()
+		case keyboard.KeyCtrlL:
+			cli.clearScreen()
 		case keyboard.KeyTab:
 			cli.tabCompletion()
+		case keyboard.KeyCtrlR:
+			cli.reverseSearch()
 		case keyboard.KeyArrowUp:
 			cli.selectHistory(-1)
 		case keyboard.KeyArrowDown:
 			cli.selectHistory(1)
-		case keyboard.KeyArrowLeft:
+		case keyboard.KeyArrowLeft, keyboard.KeyCtrlB:
 			cli.moveCursor(-1)
-		case keyboard.KeyArrowRight:
+		case keyboard.KeyArrowRight, keyboard.KeyCtrlF:
 			cli.moveCursor(1)
 		case keyboard.KeyEnter:
 			cli.runCommand()
 		}
 
 	}
-	fmt.Printf("\n")
+	cli.termPrintf("\n")
+}
+
+// run commands read from reader line by line, without keyboard interaction.
+// blank lines and lines starting with '#' are skipped. if continueOnError is
+// false, the first failing command stops the script.
+func (cli *GoCli) RunScript(reader io.Reader, continueOnError bool) error {
+	cli.isRunning = true
+	cli.initAlias()
+
+	failCount := 0
+	lineNum := 0
+	scanner := bufio.NewScanner(reader)
+	for cli.isRunning && scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := cli.execLine(line); err != nil {
+			var caretErr *caretError
+			if errors.As(err, &caretErr) {
+				fmt.Fprintf(os.Stderr, "%s\n", caretErr.format(fmt.Sprintf("line %d: ", lineNum)))
+			} else {
+				fmt.Fprintf(os.Stderr, "line %d: %v\n", lineNum, err)
+			}
+			failCount++
+			if !continueOnError {
+				break
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if failCount > 0 {
+		return fmt.Errorf("%d command(s) failed", failCount)
+	}
+
+	return nil
 }
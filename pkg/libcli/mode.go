@@ -0,0 +1,137 @@
+package libcli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// commands of root available in every mode
+var modeCommandSlice = []string{"exit", "end"}
+
+// mode entered by command elem with mode. args are the command entering mode
+// and prefix commands typed in mode. eg. # domain web01 → (domain:web01)#
+type modeContext struct {
+	name string
+	args []string
+}
+
+// set mode entered by command elem. command elem without func just enters mode
+func (elem *CommandElem) SetMode(mode string) *CommandElem {
+	elem.Mode = mode
+	return elem
+}
+
+// get prompt of current mode
+func (cli *GoCli) getPrompt() string {
+	if len(cli.modeStack) == 0 {
+		return "# "
+	}
+
+	mode := cli.modeStack[len(cli.modeStack)-1]
+	return fmt.Sprintf("(%s:%s)# ", mode.name, mode.args[len(mode.args)-1])
+}
+
+// print prompt and cli buf
+func (cli *GoCli) printLine() {
+	cli.termPrintf("%s%s", cli.getPrompt(), string(cli.buf))
+}
+
+// get args prefixed by args of current mode
+func (cli *GoCli) getModeArgs(args []string) []string {
+	if len(cli.modeStack) == 0 {
+		return args
+	}
+
+	mode := cli.modeStack[len(cli.modeStack)-1]
+	modeArgs := make([]string, 0, len(mode.args)+len(args))
+	modeArgs = append(modeArgs, mode.args...)
+	return append(modeArgs, args...)
+}
+
+// enter mode by args of command
+func (cli *GoCli) enterMode(name string, args []string) {
+	cli.modeStack = append(cli.modeStack, modeContext{
+		name: name,
+		args: append([]string{}, args...),
+	})
+}
+
+// exit current mode. return false if not in mode
+func (cli *GoCli) exitMode() bool {
+	if len(cli.modeStack) == 0 {
+		return false
+	}
+
+	cli.modeStack = cli.modeStack[:len(cli.modeStack)-1]
+	return true
+}
+
+// get matched command elem list in current mode. commands of root available
+// in every mode are listed with commands of mode
+func (cli *GoCli) getModeCommandElemList(args []string) []*CommandElem {
+	commandElemSlice := cli.getCommandElemList(cli.getModeArgs(args)...)
+	if len(cli.modeStack) == 0 || len(args) > 1 {
+		return commandElemSlice
+	}
+
+	for _, name := range modeCommandSlice {
+		commandElem := cli.commandMap[name]
+		if commandElem == nil || (len(args) == 1 && !strings.HasPrefix(name, args[0])) {
+			continue
+		}
+		commandElemSlice = append(commandElemSlice, commandElem)
+	}
+	sort.Slice(commandElemSlice,
+		func(i, j int) bool { return commandElemSlice[i].Regex < commandElemSlice[j].Regex })
+
+	return commandElemSlice
+}
+
+// match args in current mode. args not matching in mode are matched from root.
+// return command elem, args from root and error with index of args
+func (cli *GoCli) matchModeArgs(args []string) (*CommandElem, []string, *argError) {
+	modeArgs := cli.getModeArgs(args)
+	prefixLen := len(modeArgs) - len(args)
+
+	commandElem, argErr := cli.matchArgs(modeArgs)
+	if argErr == nil {
+		return commandElem, modeArgs, nil
+	}
+
+	// the first arg does not exist in mode. eg. (domain:web01)# exit
+	if prefixLen > 0 && argErr.index == prefixLen {
+		if commandElem, err := cli.matchArgs(args); err == nil {
+			return commandElem, args, nil
+		}
+	}
+
+	// error in args of mode is put on the first arg
+	argErr.index -= prefixLen
+	if argErr.index < 0 {
+		argErr.index = 0
+	}
+	if argErr.index > len(args) {
+		argErr.index = len(args)
+	}
+	return nil, nil, argErr
+}
+
+// add exit and end commands of mode
+func (cli *GoCli) modeCommand() {
+	// exit mode. quit if not in mode
+	cli.AddCommandElem(
+		NewCommandElem("exit", "exit current mode", func(args []string) error {
+			if !cli.exitMode() {
+				cli.isRunning = false
+			}
+			return nil
+		}))
+
+	// exit all modes
+	cli.AddCommandElem(
+		NewCommandElem("end", "exit to top mode", func(args []string) error {
+			cli.modeStack = make([]modeContext, 0)
+			return nil
+		}))
+}
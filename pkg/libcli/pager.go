@@ -0,0 +1,183 @@
+package libcli
+
+import (
+	"bytes"
+	"fmt"
+	"go-cli/pkg/libutil"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/eiannone/keyboard"
+	"golang.org/x/sys/unix"
+)
+
+// terminal length detected from terminal size
+const terminalLengthAuto = -1
+
+// get number of lines of terminal. 0 disables pager
+func (cli *GoCli) getTerminalLength() int {
+	if cli.terminalLength != terminalLengthAuto {
+		return cli.terminalLength
+	}
+
+	if height := atomic.LoadInt32(&cli.windowHeight); height > 0 {
+		return int(height)
+	}
+
+	winsize, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		cli.logger.Warn("failed to get terminal size: %v", err)
+		return 0
+	}
+
+	return int(winsize.Row)
+}
+
+// run line with output paged by terminal length
+func (cli *GoCli) execLineWithPager(line string) error {
+	var buf bytes.Buffer
+	out := cli.out
+	cli.out = &buf
+	err := cli.execLine(line)
+	cli.out = out
+
+	cli.page(out, buf.String())
+
+	return err
+}
+
+// read search query of pager. return empty string if canceled
+func (cli *GoCli) readPagerQuery() string {
+	query := make([]rune, 0)
+	cli.termPrintf("/")
+	for {
+		char, key, err := cli.getKey()
+		if err != nil {
+			return ""
+		}
+
+		switch {
+		case key == keyboard.KeyEnter:
+			return string(query)
+		case key == keyboard.KeyEsc:
+			return ""
+		case key == keyboard.KeyBackspace || key == keyboard.KeyBackspace2:
+			if len(query) == 0 {
+				return ""
+			}
+			query = query[:len(query)-1]
+		case key == keyboard.KeySpace:
+			query = append(query, ' ')
+		case char != 0:
+			query = append(query, char)
+		}
+
+		libutil.FprintClearLine(cli.term)
+		cli.termPrintf("/%s", string(query))
+	}
+}
+
+// find line containing query from pos. return -1 if not found
+func findLine(lines []string, query string, pos int) int {
+	for i := pos; i < len(lines); i++ {
+		if strings.Contains(lines[i], query) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// write output page by page. space shows next page, enter shows next line,
+// "/" searches forward, n repeats search and q quits
+func (cli *GoCli) page(out io.Writer, output string) {
+	length := cli.getTerminalLength()
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	if length <= 1 || len(lines) < length {
+		fmt.Fprint(out, output)
+		return
+	}
+
+	// the last line of terminal is for more prompt
+	pageSize := length - 1
+	printLines := func(start int, end int) int {
+		if end > len(lines) {
+			end = len(lines)
+		}
+		for _, line := range lines[start:end] {
+			fmt.Fprintln(out, line)
+		}
+		return end
+	}
+
+	pos := printLines(0, pageSize)
+	query := ""
+	message := ""
+	for pos < len(lines) {
+		if message != "" {
+			cli.termPrintf("%s", libutil.Highlight(message))
+			message = ""
+		} else {
+			cli.termPrintf("%s", libutil.Highlight(fmt.Sprintf("--More-- (%d%%)", pos*100/len(lines))))
+		}
+
+		char, key, err := cli.getKey()
+		libutil.FprintClearLine(cli.term)
+		if err != nil {
+			cli.logger.Warn("%v", err)
+			return
+		}
+
+		switch {
+		case char == 'q' || char == 'Q' || key == keyboard.KeyEsc:
+			return
+		case key == keyboard.KeySpace:
+			pos = printLines(pos, pos+pageSize)
+		case key == keyboard.KeyEnter:
+			pos = printLines(pos, pos+1)
+		case char == '/' || char == 'n':
+			if char == '/' {
+				query = cli.readPagerQuery()
+				libutil.FprintClearLine(cli.term)
+			}
+			if query == "" {
+				continue
+			}
+
+			found := findLine(lines, query, pos)
+			if found < 0 {
+				message = fmt.Sprintf("Pattern not found: %s", query)
+				continue
+			}
+			if found > pos {
+				fmt.Fprintln(out, "...skipping")
+			}
+			pos = printLines(found, found+pageSize-1)
+		}
+	}
+}
+
+// add terminal commands
+func (cli *GoCli) pagerCommand() {
+	// set terminal length
+	cli.AddCommandElem(
+		NewCommandElemWithoutFunc("terminal", ""),
+		NewCommandElemWithoutFunc("length", "set number of lines of pager"),
+		NewArgElem(KindInt(0, math.MaxInt32), "number of lines. 0 disables pager", func(args []string) error {
+			cli.terminalLength, _ = strconv.Atoi(args[2])
+			return nil
+		}))
+
+	// set terminal length by terminal size
+	cli.AddCommandElem(
+		NewCommandElemWithoutFunc("terminal", ""),
+		NewCommandElemWithoutFunc("length", "set number of lines of pager"),
+		NewCommandElem("auto", "number of lines of terminal size", func(args []string) error {
+			cli.terminalLength = terminalLengthAuto
+			return nil
+		}))
+}
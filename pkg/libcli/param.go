@@ -0,0 +1,216 @@
+package libcli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keyword value parameter of command. parameters follow the command in any order
+// eg. # domain create name web01 memory 8G cpu 4
+type Param struct {
+	Name     string
+	Kind     *Kind
+	Desc     string
+	Default  string
+	Required bool
+	// list candidates of value for tab completion
+	Completer func(args []string) []string
+}
+
+func NewParam(name string, kind *Kind, desc string) *Param {
+	return &Param{
+		Name: name,
+		Kind: kind,
+		Desc: desc,
+	}
+}
+
+// set param required
+func (param *Param) SetRequired() *Param {
+	param.Required = true
+	return param
+}
+
+// set default value used when param is omitted
+func (param *Param) SetDefault(value string) *Param {
+	param.Default = value
+	return param
+}
+
+// set completer of param value
+func (param *Param) SetCompleter(completer func(args []string) []string) *Param {
+	param.Completer = completer
+	return param
+}
+
+// get description of param with required or default value
+func (param *Param) getDesc() string {
+	switch {
+	case param.Required:
+		return fmt.Sprintf("%s (required)", param.Desc)
+	case param.Default != "":
+		return fmt.Sprintf("%s (default %s)", param.Desc, param.Default)
+	default:
+		return param.Desc
+	}
+}
+
+// command elem with keyword value params. f is called with values of params by name
+func NewCommandElemWithParams(regex string, desc string, params []*Param, f func(params map[string]string) error) *CommandElem {
+	return NewCommandElemWithArgsParams(regex, desc, params, func(args []string, params map[string]string) error {
+		return f(params)
+	})
+}
+
+// command elem with keyword value params. f is also called with args before params.
+// eg. args of # link name eth0 up netns red are link, name, eth0 and up
+func NewCommandElemWithArgsParams(regex string, desc string, params []*Param,
+	f func(args []string, params map[string]string) error) *CommandElem {
+	return &CommandElem{
+		Regex:      regex,
+		Desc:       desc,
+		Params:     params,
+		ParamFunc:  f,
+		commandMap: make(map[string]*CommandElem),
+	}
+}
+
+// command elem of argument kind with keyword value params. f is called with args before params.
+// eg. # link name eth0 addr add 10.0.0.1/24 netns red
+func NewArgElemWithParams(kind *Kind, desc string, params []*Param,
+	f func(args []string, params map[string]string) error) *CommandElem {
+	return &CommandElem{
+		Regex:      kind.Regex,
+		Desc:       desc,
+		Kind:       kind,
+		Params:     params,
+		ParamFunc:  f,
+		commandMap: make(map[string]*CommandElem),
+	}
+}
+
+// get func parsing args after depth to params and calling ParamFunc with args before params
+func (elem *CommandElem) getParamFunc(depth int) func(args []string) error {
+	return func(args []string) error {
+		params, err := elem.parseParams(args[depth:])
+		if err != nil {
+			return err
+		}
+
+		return elem.ParamFunc(args[:depth], params)
+	}
+}
+
+// get names of params
+func (elem *CommandElem) getParamNames() []string {
+	names := make([]string, 0)
+	for _, param := range elem.Params {
+		names = append(names, param.Name)
+	}
+
+	return names
+}
+
+// get param by name
+func (elem *CommandElem) getParam(name string) *Param {
+	for _, param := range elem.Params {
+		if param.Name == name {
+			return param
+		}
+	}
+
+	return nil
+}
+
+// parse keyword value args to values of params. omitted params get default value
+func (elem *CommandElem) parseParams(args []string) (map[string]string, *argError) {
+	values := make(map[string]string)
+	for i := 0; i < len(args); i += 2 {
+		param := elem.getParam(args[i])
+		if param == nil {
+			return nil, &argError{index: i, err: fmt.Errorf("unknown parameter \"%s\", expected %s",
+				args[i], strings.Join(elem.getParamNames(), ", "))}
+		}
+
+		if _, exist := values[param.Name]; exist {
+			return nil, &argError{index: i, err: fmt.Errorf("duplicated parameter \"%s\"", param.Name)}
+		}
+
+		if i+1 >= len(args) {
+			return nil, &argError{index: i + 1, err: fmt.Errorf("parameter \"%s\" requires %s", param.Name, param.Kind.Name)}
+		}
+
+		if err := param.Kind.validate(args[i+1]); err != nil {
+			return nil, &argError{index: i + 1, err: err}
+		}
+		values[param.Name] = args[i+1]
+	}
+
+	for _, param := range elem.Params {
+		if _, exist := values[param.Name]; exist {
+			continue
+		}
+
+		if param.Required {
+			return nil, &argError{index: len(args), err: fmt.Errorf("missing required parameter \"%s\"", param.Name)}
+		}
+
+		if param.Default != "" {
+			values[param.Name] = param.Default
+		}
+	}
+
+	return values, nil
+}
+
+// get command elem list of params for help and tab completion.
+// unused keywords are listed, or the value of the last keyword
+func (elem *CommandElem) getParamElemList(args []string) []*CommandElem {
+	used := make(map[string]bool)
+	for i := 0; i < len(args); i += 2 {
+		param := elem.getParam(args[i])
+		if param == nil || used[param.Name] {
+			// incomplete keyword
+			if i == len(args)-1 {
+				return elem.getKeywordElemList(used, args[i])
+			}
+			return []*CommandElem{}
+		}
+
+		// value of keyword is not typed yet
+		if i+1 >= len(args) {
+			return []*CommandElem{{
+				Regex:      param.Kind.Regex,
+				Desc:       param.Desc,
+				Kind:       param.Kind,
+				Completer:  param.Completer,
+				commandMap: make(map[string]*CommandElem),
+			}}
+		}
+
+		if !param.Kind.match(args[i+1]) {
+			return []*CommandElem{}
+		}
+		used[param.Name] = true
+	}
+
+	return elem.getKeywordElemList(used, "")
+}
+
+// get command elem list of unused keywords starting with prefix
+func (elem *CommandElem) getKeywordElemList(used map[string]bool, prefix string) []*CommandElem {
+	commandElemSlice := make([]*CommandElem, 0)
+	for _, param := range elem.Params {
+		if used[param.Name] || !strings.HasPrefix(param.Name, prefix) {
+			continue
+		}
+
+		commandElemSlice = append(commandElemSlice, &CommandElem{
+			Regex:      param.Name,
+			Desc:       param.getDesc(),
+			commandMap: make(map[string]*CommandElem),
+		})
+	}
+
+	return commandElemSlice
+}
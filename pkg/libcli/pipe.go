@@ -0,0 +1,251 @@
+package libcli
+
+import (
+	"bytes"
+	"fmt"
+	"go-cli/pkg/libutil"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// captured output of command. header is the table header if exists
+type pipeOutput struct {
+	header string
+	lines  []string
+}
+
+// pipe filter applied to captured output
+type pipeFilter func(output *pipeOutput) error
+
+// pipe stage help string and description
+var pipeHelpSlice = [][]string{
+	{"include REGEX", "show lines matching regex"},
+	{"exclude REGEX", "hide lines matching regex"},
+	{"begin REGEX", "show lines from the first line matching regex"},
+	{"count", "count lines"},
+	{"sort by COLUMN", "sort lines by table column"},
+	{"head NUMBER", "show first n lines"},
+	{"FORMAT(table|wide|json|yaml|csv)", "output format"},
+}
+
+// split args to command args and pipe stage args by "|"
+func splitPipe(args []string) ([]string, [][]string) {
+	stages := make([][]string, 0)
+	for i := len(args) - 1; i >= 0; i-- {
+		if args[i] != "|" {
+			continue
+		}
+		stages = append([][]string{args[i+1:]}, stages...)
+		args = args[:i]
+	}
+
+	return args, stages
+}
+
+// compile regex of pipe stage args
+func compilePipeRegex(stage []string) (*regexp.Regexp, error) {
+	if len(stage) < 2 {
+		return nil, fmt.Errorf("%s: regex is required", stage[0])
+	}
+
+	return regexp.Compile(strings.Join(stage[1:], " "))
+}
+
+// parse pipe stage to filter. output format stage returns format
+func parsePipeStage(stage []string) (pipeFilter, string, error) {
+	if len(stage) == 0 {
+		return nil, "", fmt.Errorf("empty pipe stage")
+	}
+
+	if libutil.IsOutputFormat(stage[0]) && len(stage) == 1 {
+		return nil, stage[0], nil
+	}
+
+	switch stage[0] {
+	case "include", "exclude":
+		regex, err := compilePipeRegex(stage)
+		if err != nil {
+			return nil, "", err
+		}
+
+		include := stage[0] == "include"
+		return func(output *pipeOutput) error {
+			lines := make([]string, 0)
+			for _, line := range output.lines {
+				if regex.MatchString(line) == include {
+					lines = append(lines, line)
+				}
+			}
+			output.lines = lines
+			return nil
+		}, "", nil
+	case "begin":
+		regex, err := compilePipeRegex(stage)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return func(output *pipeOutput) error {
+			for i, line := range output.lines {
+				if regex.MatchString(line) {
+					output.lines = output.lines[i:]
+					return nil
+				}
+			}
+			output.lines = []string{}
+			return nil
+		}, "", nil
+	case "count":
+		return func(output *pipeOutput) error {
+			output.lines = []string{fmt.Sprintf("Count: %d", len(output.lines))}
+			output.header = ""
+			return nil
+		}, "", nil
+	case "head":
+		if len(stage) != 2 {
+			return nil, "", fmt.Errorf("head: number is required")
+		}
+
+		n, err := strconv.Atoi(stage[1])
+		if err != nil || n < 0 {
+			return nil, "", fmt.Errorf("head: invalid number \"%s\"", stage[1])
+		}
+
+		return func(output *pipeOutput) error {
+			if len(output.lines) > n {
+				output.lines = output.lines[:n]
+			}
+			return nil
+		}, "", nil
+	case "sort":
+		if len(stage) != 3 || stage[1] != "by" {
+			return nil, "", fmt.Errorf("sort: usage is \"sort by COLUMN\"")
+		}
+
+		column := stage[2]
+		return func(output *pipeOutput) error {
+			start, end, err := getTableColumnRange(output.header, column)
+			if err != nil {
+				return err
+			}
+
+			lines := output.lines
+			sort.SliceStable(lines, func(i, j int) bool {
+				return lessCell(getTableCell(lines[i], start, end), getTableCell(lines[j], start, end))
+			})
+			return nil
+		}, "", nil
+	default:
+		return nil, "", fmt.Errorf("unknown pipe \"%s\"", stage[0])
+	}
+}
+
+// get column range of right aligned table by header
+func getTableColumnRange(header string, column string) (int, int, error) {
+	if header == "" {
+		return 0, 0, fmt.Errorf("sort: output has no table header")
+	}
+
+	start := 0
+	for _, name := range strings.Fields(header) {
+		end := strings.Index(header[start:], name) + start + len(name)
+		if strings.EqualFold(name, column) {
+			return start, end, nil
+		}
+		start = end
+	}
+
+	return 0, 0, fmt.Errorf("sort: column \"%s\" does not exist", column)
+}
+
+// get trimmed table cell in range of line
+func getTableCell(line string, start int, end int) string {
+	if start > len(line) {
+		return ""
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+
+	return strings.TrimSpace(line[start:end])
+}
+
+// compare cells as number if both are numbers, otherwise as string
+func lessCell(a string, b string) bool {
+	aNum, aErr := strconv.ParseFloat(a, 64)
+	bNum, bErr := strconv.ParseFloat(b, 64)
+	if aErr == nil && bErr == nil {
+		return aNum < bNum
+	}
+
+	return a < b
+}
+
+// run command with pipe stages. output of command is captured and filtered
+func (cli *GoCli) runPipe(commandElem *CommandElem, args []string, stages [][]string) error {
+	filters := make([]pipeFilter, 0)
+	outputFormat := cli.outputFormat
+	for _, stage := range stages {
+		filter, format, err := parsePipeStage(stage)
+		if err != nil {
+			return err
+		}
+
+		if format != "" {
+			outputFormat = format
+			continue
+		}
+		filters = append(filters, filter)
+	}
+
+	format := cli.outputFormat
+	cli.outputFormat = outputFormat
+	defer func() {
+		cli.outputFormat = format
+	}()
+
+	if len(filters) == 0 {
+		return commandElem.Func(args)
+	}
+
+	// capture output of command
+	var buf bytes.Buffer
+	out := cli.out
+	cli.out = &buf
+	cli.pipeHeader = ""
+	cli.isPiping = true
+
+	err := commandElem.Func(args)
+
+	cli.out = out
+	cli.isPiping = false
+	if err != nil {
+		io.Copy(cli.out, &buf)
+		return err
+	}
+
+	output := &pipeOutput{header: cli.pipeHeader, lines: make([]string, 0)}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.TrimSpace(line) != "" {
+			output.lines = append(output.lines, line)
+		}
+	}
+
+	for _, filter := range filters {
+		if err := filter(output); err != nil {
+			return err
+		}
+	}
+
+	if output.header != "" {
+		fmt.Fprintln(cli.out, output.header)
+	}
+	for _, line := range output.lines {
+		fmt.Fprintln(cli.out, line)
+	}
+
+	return nil
+}
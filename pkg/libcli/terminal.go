@@ -0,0 +1,122 @@
+package libcli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/eiannone/keyboard"
+)
+
+// keys of escape sequences after esc
+var escapeSequenceMap = map[string]keyboard.Key{
+	"[A":  keyboard.KeyArrowUp,
+	"[B":  keyboard.KeyArrowDown,
+	"[C":  keyboard.KeyArrowRight,
+	"[D":  keyboard.KeyArrowLeft,
+	"[H":  keyboard.KeyHome,
+	"[F":  keyboard.KeyEnd,
+	"OA":  keyboard.KeyArrowUp,
+	"OB":  keyboard.KeyArrowDown,
+	"OC":  keyboard.KeyArrowRight,
+	"OD":  keyboard.KeyArrowLeft,
+	"OH":  keyboard.KeyHome,
+	"OF":  keyboard.KeyEnd,
+	"[1~": keyboard.KeyHome,
+	"[2~": keyboard.KeyInsert,
+	"[3~": keyboard.KeyDelete,
+	"[4~": keyboard.KeyEnd,
+	"[5~": keyboard.KeyPgup,
+	"[6~": keyboard.KeyPgdn,
+}
+
+// writer converting "\n" to "\r\n" for terminal in raw mode
+type crlfWriter struct {
+	w io.Writer
+}
+
+func (w *crlfWriter) Write(p []byte) (int, error) {
+	if _, err := w.w.Write(bytes.ReplaceAll(p, []byte("\n"), []byte("\r\n"))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// parse key event from the head of data. return size of parsed bytes
+func parseKey(data []byte) (int, keyboard.KeyEvent) {
+	switch {
+	case data[0] == 0x1b && len(data) == 1:
+		return 1, keyboard.KeyEvent{Key: keyboard.KeyEsc}
+	case data[0] == 0x1b:
+		for sequence, key := range escapeSequenceMap {
+			if bytes.HasPrefix(data[1:], []byte(sequence)) {
+				return 1 + len(sequence), keyboard.KeyEvent{Key: key}
+			}
+		}
+
+		// alt key combination comes as esc with char
+		r, size := utf8.DecodeRune(data[1:])
+		return 1 + size, keyboard.KeyEvent{Key: keyboard.KeyEsc, Rune: r}
+	case data[0] == ' ':
+		return 1, keyboard.KeyEvent{Key: keyboard.KeySpace}
+	case data[0] < 0x20 || data[0] == 0x7f:
+		return 1, keyboard.KeyEvent{Key: keyboard.Key(data[0])}
+	default:
+		r, size := utf8.DecodeRune(data)
+		return size, keyboard.KeyEvent{Rune: r}
+	}
+}
+
+// read key events from terminal reader. channel is closed at the end of reader
+func readKeys(reader io.Reader) <-chan keyboard.KeyEvent {
+	keys := make(chan keyboard.KeyEvent, 10)
+	go func() {
+		defer close(keys)
+
+		buf := make([]byte, 256)
+		for {
+			n, err := reader.Read(buf)
+			for data := buf[:n]; len(data) > 0; {
+				size, event := parseKey(data)
+				keys <- event
+				data = data[size:]
+			}
+
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return keys
+}
+
+// set terminal of cli instead of stdin and stdout. eg. pty of ssh session
+func (cli *GoCli) SetTerminal(reader io.Reader, writer io.Writer) {
+	cli.term = &crlfWriter{w: writer}
+	cli.out = cli.term
+	cli.keys = readKeys(reader)
+}
+
+// set number of lines of terminal window for pager
+func (cli *GoCli) SetWindowHeight(height int) {
+	atomic.StoreInt32(&cli.windowHeight, int32(height))
+}
+
+// get key from terminal. io.EOF if terminal is closed
+func (cli *GoCli) getKey() (rune, keyboard.Key, error) {
+	event, ok := <-cli.keys
+	if !ok {
+		return 0, 0, io.EOF
+	}
+
+	return event.Rune, event.Key, event.Err
+}
+
+// print to terminal
+func (cli *GoCli) termPrintf(format string, a ...any) {
+	fmt.Fprintf(cli.term, format, a...)
+}
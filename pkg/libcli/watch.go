@@ -0,0 +1,164 @@
+package libcli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go-cli/pkg/libutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eiannone/keyboard"
+)
+
+// default interval of watch in seconds
+const defaultWatchInterval = 2
+
+// cell of output line separated by spaces
+var watchCellRegexp = regexp.MustCompile(`\S+`)
+
+// highlight cells of line changed from prev line
+func highlightChanges(prev string, line string) string {
+	prevCells := watchCellRegexp.FindAllString(prev, -1)
+
+	var sb strings.Builder
+	pos := 0
+	for i, index := range watchCellRegexp.FindAllStringIndex(line, -1) {
+		cell := line[index[0]:index[1]]
+		sb.WriteString(line[pos:index[0]])
+		if i < len(prevCells) && prevCells[i] == cell {
+			sb.WriteString(cell)
+		} else {
+			sb.WriteString(libutil.Highlight(cell))
+		}
+		pos = index[1]
+	}
+	sb.WriteString(line[pos:])
+
+	return sb.String()
+}
+
+// is show command. show must be the first command token, a keyword before any
+// argument except arguments entering mode, so an argument named show is not
+// a show command. eg. # link show, # domain web01 show, (domain:web01)# show
+func (cli *GoCli) isShowCommand(modeArgs []string, prefixLen int) bool {
+	commandMap := cli.commandMap
+	for i, arg := range modeArgs {
+		commandElem := findCommandElem(commandMap, arg)
+		if commandElem == nil {
+			return false
+		}
+
+		// args of mode are not typed
+		if i >= prefixLen {
+			if !commandElem.isKeyword() && commandElem.Mode == "" {
+				return false
+			}
+			if commandElem.isKeyword() && arg == "show" {
+				return true
+			}
+		}
+
+		// the rest of args are params or line
+		if len(commandElem.Params) > 0 || commandElem.isRest() {
+			return false
+		}
+		commandMap = commandElem.commandMap
+	}
+
+	return false
+}
+
+// run show command of line every interval until key is pressed
+func (cli *GoCli) watch(interval time.Duration, line string) error {
+	args, _ := splitPipe(libutil.RemoveEmptyString(strings.Split(line, " ")))
+	_, modeArgs, argErr := cli.matchModeArgs(args)
+	if argErr != nil {
+		return newCaretError(line, args, argErr.index, argErr.err)
+	}
+
+	if !cli.isShowCommand(modeArgs, len(modeArgs)-len(args)) {
+		return fmt.Errorf("only show commands can be watched")
+	}
+
+	// keyboard is not opened when running script
+	keys := cli.keys
+	if keys == nil {
+		var err error
+		if keys, err = keyboard.GetKeys(10); err != nil {
+			return err
+		}
+		defer func() {
+			_ = keyboard.Close()
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prevLines []string
+	for {
+		// capture output of command
+		var buf bytes.Buffer
+		out := cli.out
+		cli.out = &buf
+		err := cli.execLine(line)
+		cli.out = out
+
+		libutil.FprintClearScreen(cli.term)
+		cli.termPrintf("Every %v: %s    %s\n\n", interval, line, time.Now().Format(time.DateTime))
+
+		lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+		for i, each := range lines {
+			// the first output has nothing to compare
+			if prevLines != nil {
+				prev := ""
+				if i < len(prevLines) {
+					prev = prevLines[i]
+				}
+				each = highlightChanges(prev, each)
+			}
+			cli.termPrintf("%s\n", each)
+		}
+		prevLines = lines
+
+		if err != nil {
+			var caretErr *caretError
+			if errors.As(err, &caretErr) {
+				cli.termPrintf("%s\n", caretErr.format(""))
+			} else {
+				cli.termPrintf("%v\n", err)
+			}
+		}
+		cli.termPrintf("\nPress any key to exit\n")
+
+		select {
+		case <-keys:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// add watch commands
+func (cli *GoCli) watchCommand() {
+	// watch command every default interval
+	cli.AddCommandElem(
+		NewCommandElemWithoutFunc("watch", "run show command periodically until key is pressed"),
+		NewArgElem(KindLine, "show command", func(args []string) error {
+			return cli.watch(defaultWatchInterval*time.Second, strings.Join(args[1:], " "))
+		}))
+
+	// watch command every interval
+	cli.AddCommandElem(
+		NewCommandElemWithoutFunc("watch", "run show command periodically until key is pressed"),
+		NewCommandElemWithoutFunc("interval", "interval in seconds"),
+		NewArgElemWithoutFunc(KindInt(1, math.MaxInt32), "interval in seconds"),
+		NewArgElem(KindLine, "show command", func(args []string) error {
+			interval, _ := strconv.Atoi(args[2])
+			return cli.watch(time.Duration(interval)*time.Second, strings.Join(args[3:], " "))
+		}))
+}
@@ -0,0 +1,169 @@
+package libhttp
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// openapi document object
+type object = map[string]interface{}
+
+var pathVarRegexp = regexp.MustCompile(`\{([a-zA-Z]+)\}`)
+
+// get schema of proto field
+func getFieldSchema(field protoreflect.FieldDescriptor, schemas object) object {
+	var schema object
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		schema = object{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		schema = object{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson encodes 64 bit integer as string
+		schema = object{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		schema = object{"type": "number"}
+	case protoreflect.BytesKind:
+		schema = object{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := make([]string, 0)
+		enumValues := field.Enum().Values()
+		for i := 0; i < enumValues.Len(); i++ {
+			values = append(values, string(enumValues.Get(i).Name()))
+		}
+		schema = object{"type": "string", "enum": values}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		schema = object{"$ref": addSchema(field.Message(), schemas)}
+	default:
+		schema = object{"type": "string"}
+	}
+
+	if field.IsList() {
+		return object{"type": "array", "items": schema}
+	}
+
+	return schema
+}
+
+// add schema of proto message to schemas and get ref of it
+func addSchema(message protoreflect.MessageDescriptor, schemas object) string {
+	name := string(message.Name())
+	ref := "#/components/schemas/" + name
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+
+	properties := object{}
+	schemas[name] = object{"type": "object", "properties": properties}
+
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		properties[field.JSONName()] = getFieldSchema(field, schemas)
+	}
+
+	return ref
+}
+
+// get operation of route
+func (rt *route) getOperation(schemas object) object {
+	parameters := make([]object, 0)
+	pathVars := make(map[string]bool)
+	for _, match := range pathVarRegexp.FindAllStringSubmatch(rt.path, -1) {
+		pathVars[match[1]] = true
+		parameters = append(parameters, object{
+			"name": match[1], "in": "path", "required": true, "schema": object{"type": "string"},
+		})
+	}
+
+	// fields of query are url query of get, and json body of others
+	query := rt.query.ProtoReflect().Descriptor()
+	if rt.method == http.MethodGet {
+		fields := query.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if pathVars[field.JSONName()] || field.Kind() == protoreflect.MessageKind || field.IsList() {
+				continue
+			}
+			parameters = append(parameters, object{
+				"name": field.JSONName(), "in": "query", "schema": getFieldSchema(field, schemas),
+			})
+		}
+	}
+
+	responseSchema := object{"$ref": addSchema(rt.resp.ProtoReflect().Descriptor(), schemas)}
+	if rt.stream {
+		responseSchema = object{"type": "array", "items": responseSchema}
+	}
+
+	status := rt.getStatus()
+	operation := object{
+		"operationId": rt.rpc,
+		"summary":     rt.summary,
+		"tags":        []string{rt.getTag()},
+		"parameters":  parameters,
+		"responses": object{
+			strconv.Itoa(status): object{
+				"description": http.StatusText(status),
+				"content":     object{"application/json": object{"schema": responseSchema}},
+			},
+			"default": object{
+				"description": "error",
+				"content":     object{"application/json": object{"schema": object{"$ref": "#/components/schemas/Error"}}},
+			},
+		},
+	}
+
+	if rt.method != http.MethodGet && query.Fields().Len() > len(pathVars) {
+		operation["requestBody"] = object{
+			"content": object{"application/json": object{"schema": object{"$ref": addSchema(query, schemas)}}},
+		}
+	}
+
+	return operation
+}
+
+// get openapi document of routes
+func newOpenApi(routes []*route) object {
+	schemas := object{
+		"Error": object{
+			"type": "object",
+			"properties": object{
+				"code":    object{"type": "string"},
+				"message": object{"type": "string"},
+			},
+		},
+	}
+
+	paths := object{}
+	for _, rt := range routes {
+		pathItem, ok := paths[rt.path].(object)
+		if !ok {
+			pathItem = object{}
+			paths[rt.path] = pathItem
+		}
+		pathItem[strings.ToLower(rt.method)] = rt.getOperation(schemas)
+	}
+
+	return object{
+		"openapi": "3.0.3",
+		"info": object{
+			"title":   "go-cli",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": schemas,
+			"securitySchemes": object{
+				"bearer": object{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []object{{"bearer": []string{}}, {}},
+	}
+}
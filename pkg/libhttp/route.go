@@ -0,0 +1,205 @@
+package libhttp
+
+import (
+	"context"
+	"go-cli/pkg/libnet/networker"
+	"go-cli/pkg/libvm/vmer"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// route of rest api to grpc method
+type route struct {
+	method  string
+	path    string
+	rpc     string
+	summary string
+	stream  bool
+	query   proto.Message
+	resp    proto.Message
+	call    func(ctx context.Context, query proto.Message) ([]proto.Message, error)
+}
+
+// route of unary method
+func unary[Q, R proto.Message](method, path, rpc, summary string,
+	call func(context.Context, Q, ...grpc.CallOption) (R, error)) *route {
+	var query Q
+	var resp R
+	return &route{
+		method:  method,
+		path:    path,
+		rpc:     rpc,
+		summary: summary,
+		query:   query.ProtoReflect().Type().New().Interface(),
+		resp:    resp.ProtoReflect().Type().New().Interface(),
+		call: func(ctx context.Context, query proto.Message) ([]proto.Message, error) {
+			resp, err := call(ctx, query.(Q))
+			if err != nil {
+				return nil, err
+			}
+			return []proto.Message{resp}, nil
+		},
+	}
+}
+
+// route of server stream method. messages are responded as json array
+func stream[Q, R proto.Message, S interface{ Recv() (R, error) }](method, path, rpc, summary string,
+	call func(context.Context, Q, ...grpc.CallOption) (S, error)) *route {
+	var query Q
+	var resp R
+	return &route{
+		method:  method,
+		path:    path,
+		rpc:     rpc,
+		summary: summary,
+		stream:  true,
+		query:   query.ProtoReflect().Type().New().Interface(),
+		resp:    resp.ProtoReflect().Type().New().Interface(),
+		call: func(ctx context.Context, query proto.Message) ([]proto.Message, error) {
+			s, err := call(ctx, query.(Q))
+			if err != nil {
+				return nil, err
+			}
+
+			messages := make([]proto.Message, 0)
+			for {
+				msg, err := s.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				messages = append(messages, msg)
+			}
+			return messages, nil
+		},
+	}
+}
+
+// get status of success. created for post of add, create and upload
+func (rt *route) getStatus() int {
+	if rt.method == http.MethodPost {
+		for _, verb := range []string{"Add", "Create", "Upload"} {
+			if strings.HasPrefix(rt.rpc, verb) {
+				return http.StatusCreated
+			}
+		}
+	}
+
+	return http.StatusOK
+}
+
+// get tag of route by first path segment. eg. /v1/links/{name}/up -> links
+func (rt *route) getTag() string {
+	return strings.Split(strings.TrimPrefix(rt.path, "/v1/"), "/")[0]
+}
+
+// get routes of networker and vmer rpcs
+func getRoutes(net networker.NetworkerClient, vm vmer.VmerClient) []*route {
+	get, post, put, patch, del := http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete
+
+	return []*route{
+		// networker
+		unary(get, "/v1/links", "ShowNetLink", "show links", net.ShowNetLink),
+		unary(put, "/v1/links/{name}/mac", "SetNetLinkMac", "set mac address of link", net.SetNetLinkMac),
+		unary(post, "/v1/links/{name}/up", "SetNetLinkUp", "set link up", net.SetNetLinkUp),
+		unary(post, "/v1/links/{name}/down", "SetNetLinkDown", "set link down", net.SetNetLinkDown),
+
+		unary(get, "/v1/bridges", "ShowBridge", "show bridges", net.ShowBridge),
+		unary(post, "/v1/bridges", "AddBridge", "add bridge", net.AddBridge),
+		unary(del, "/v1/bridges/{name}", "DelBridge", "delete bridge", net.DelBridge),
+		unary(get, "/v1/bridges/{name}/slaves", "ShowBridgeSlave", "show slaves of bridge", net.ShowBridgeSlave),
+		unary(put, "/v1/bridges/{name}/slaves/{slaveName}", "SetBridgeMaster", "set master of slave", net.SetBridgeMaster),
+		unary(del, "/v1/bridges/{name}/slaves/{slaveName}", "UnsetBridgeMaster", "unset master of slave", net.UnsetBridgeMaster),
+
+		unary(get, "/v1/bonds", "ShowBond", "show bonds", net.ShowBond),
+		unary(post, "/v1/bonds", "AddBond", "add bond", net.AddBond),
+		unary(del, "/v1/bonds/{name}", "DelBond", "delete bond", net.DelBond),
+		unary(get, "/v1/bonds/{name}/slaves", "ShowBondSlave", "show state of slaves of bond", net.ShowBondSlave),
+		unary(put, "/v1/bonds/{name}/slaves/{slaveName}", "SetBondMaster", "enslave link to bond", net.SetBondMaster),
+		unary(del, "/v1/bonds/{name}/slaves/{slaveName}", "UnsetBondMaster", "release slave from bond", net.UnsetBondMaster),
+
+		unary(get, "/v1/veths", "ShowVeth", "show veths", net.ShowVeth),
+		unary(post, "/v1/veths", "AddVeth", "add veth pair", net.AddVeth),
+		unary(del, "/v1/veths/{name}", "DelVeth", "delete veth pair", net.DelVeth),
+
+		unary(get, "/v1/vlans", "ShowVlan", "show vlans", net.ShowVlan),
+		unary(post, "/v1/vlans", "AddVlan", "add vlan", net.AddVlan),
+		unary(del, "/v1/vlans/{name}", "DelVlan", "delete vlan", net.DelVlan),
+
+		unary(get, "/v1/vxlans", "ShowVxlan", "show vxlans", net.ShowVxlan),
+		unary(post, "/v1/vxlans", "AddVxlan", "add vxlan", net.AddVxlan),
+		unary(del, "/v1/vxlans/{name}", "DelVxlan", "delete vxlan", net.DelVxlan),
+
+		unary(get, "/v1/macvlans", "ShowMacvlan", "show macvlans", net.ShowMacvlan),
+		unary(post, "/v1/macvlans", "AddMacvlan", "add macvlan", net.AddMacvlan),
+		unary(del, "/v1/macvlans/{name}", "DelMacvlan", "delete macvlan", net.DelMacvlan),
+
+		unary(get, "/v1/ipvlans", "ShowIpvlan", "show ipvlans", net.ShowIpvlan),
+		unary(post, "/v1/ipvlans", "AddIpvlan", "add ipvlan", net.AddIpvlan),
+		unary(del, "/v1/ipvlans/{name}", "DelIpvlan", "delete ipvlan", net.DelIpvlan),
+
+		unary(get, "/v1/tuntaps", "ShowTuntap", "show tuntaps", net.ShowTuntap),
+		unary(post, "/v1/tuntaps", "AddTuntap", "add tuntap", net.AddTuntap),
+		unary(del, "/v1/tuntaps/{name}", "DelTuntap", "delete tuntap", net.DelTuntap),
+
+		unary(get, "/v1/fdbs", "ShowFdb", "show fdb entries", net.ShowFdb),
+		unary(post, "/v1/fdbs", "AddFdb", "add static fdb entry", net.AddFdb),
+		unary(del, "/v1/fdbs", "DelFdb", "delete static fdb entry", net.DelFdb),
+
+		unary(get, "/v1/netns", "ShowNetns", "show netns", net.ShowNetns),
+		unary(post, "/v1/netns", "AddNetns", "add netns", net.AddNetns),
+		unary(del, "/v1/netns/{name}", "DelNetns", "delete netns", net.DelNetns),
+		unary(put, "/v1/netns/{name}/links/{linkName}", "SetNetnsLink", "move link into netns", net.SetNetnsLink),
+		unary(del, "/v1/netns/{name}/links/{linkName}", "UnsetNetnsLink", "move link out of netns", net.UnsetNetnsLink),
+
+		unary(get, "/v1/addrs", "ShowAddr", "show addrs", net.ShowAddr),
+		unary(post, "/v1/addrs", "AddAddr", "stage adding addr", net.AddAddr),
+		unary(del, "/v1/addrs", "DelAddr", "stage deleting addr", net.DelAddr),
+
+		unary(get, "/v1/rules", "ShowRule", "show rules", net.ShowRule),
+		unary(post, "/v1/rules", "AddRule", "stage adding rule", net.AddRule),
+		unary(del, "/v1/rules", "DelRule", "stage deleting rule", net.DelRule),
+
+		unary(get, "/v1/routes", "ShowRoute", "show routes", net.ShowRoute),
+		unary(post, "/v1/routes", "AddRoute", "stage adding route", net.AddRoute),
+		unary(del, "/v1/routes", "DelRoute", "stage deleting route", net.DelRoute),
+
+		unary(get, "/v1/candidate", "ShowCandidate", "show staged changes", net.ShowCandidate),
+		unary(del, "/v1/candidate", "DiscardCandidate", "discard staged changes", net.DiscardCandidate),
+		unary(post, "/v1/commit", "Commit", "commit staged changes", net.Commit),
+		unary(post, "/v1/commit/confirm", "ConfirmCommit", "confirm commit", net.ConfirmCommit),
+
+		// vmer
+		stream[*vmer.BaseImageMessage, *vmer.BaseImageMessage](get, "/v1/base-images", "ShowBaseImages", "show base images", vm.ShowBaseImages),
+		unary(post, "/v1/base-images", "UploadBaseImage", "upload base image", vm.UploadBaseImage),
+		unary(del, "/v1/base-images/{name}", "DeleteBaseImage", "delete base image", vm.DeleteBaseImage),
+
+		stream[*vmer.KeyMessage, *vmer.KeyMessage](get, "/v1/keys", "ShowKeys", "show keys", vm.ShowKeys),
+		unary(post, "/v1/keys", "UploadKey", "upload key", vm.UploadKey),
+		unary(post, "/v1/keys/{name}/generate", "CreateKey", "generate key", vm.CreateKey),
+		unary(del, "/v1/keys/{name}", "DeleteKey", "delete key", vm.DeleteKey),
+
+		stream[*vmer.NetworkMessage, *vmer.NetworkMessage](get, "/v1/networks", "ShowNetworks", "show networks", vm.ShowNetworks),
+		unary(post, "/v1/networks", "CreateNetwork", "create network", vm.CreateNetwork),
+		unary(del, "/v1/networks/{name}", "DeleteNetwork", "delete network", vm.DeleteNetwork),
+
+		stream[*vmer.VolumeMessage, *vmer.VolumeMessage](get, "/v1/volumes", "ShowVolumes", "show volumes", vm.ShowVolumes),
+		unary(post, "/v1/volumes", "CreateVolume", "create volume", vm.CreateVolume),
+		unary(del, "/v1/volumes/{name}", "DeleteVolume", "delete volume", vm.DeleteVolume),
+
+		stream[*vmer.DomainMessage, *vmer.DomainMessage](get, "/v1/domains", "ShowDomains", "show domains", vm.ShowDomains),
+		unary(post, "/v1/domains", "CreateDomain", "create domain", vm.CreateDomain),
+		unary(del, "/v1/domains/{name}", "DeleteDomain", "delete domain", vm.DeleteDomain),
+		unary(patch, "/v1/domains/{name}", "UpdateDomain", "update cpu and memory of domain", vm.UpdateDomain),
+		unary(post, "/v1/domains/{name}/start", "StartDomain", "start domain", vm.StartDomain),
+		unary(post, "/v1/domains/{name}/stop", "StopDomain", "stop domain", vm.StopDomain),
+
+		stream[*vmer.AuditQuery, *vmer.AuditMessage](get, "/v1/audits", "ShowAudits", "show audit logs", vm.ShowAudits),
+	}
+}
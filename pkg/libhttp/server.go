@@ -0,0 +1,360 @@
+package libhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-cli/pkg/libnet/networker"
+	"go-cli/pkg/libutil"
+	"go-cli/pkg/libvm/vmer"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	nblogger "github.com/banaconda/nb-logger"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// timeout of grpc call of request
+const requestTimeout = 30 * time.Second
+
+// max size of json body of request
+const maxBodySize = 1 << 20
+
+var logger nblogger.Logger
+
+// http server is set by serving goroutine and shut down by others
+var httpServer *http.Server
+var httpServerMutex sync.Mutex
+
+// config of daemon forwarding identity of callers
+var httpConfig *libutil.Config
+
+// error of rest api
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// http status of grpc code
+var httpStatusMap = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// get http status of grpc error
+func getHttpStatus(code codes.Code) int {
+	if httpStatus, ok := httpStatusMap[code]; ok {
+		return httpStatus
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeJson(w http.ResponseWriter, httpStatus int, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(append(data, '\n'))
+}
+
+func writeError(w http.ResponseWriter, httpStatus int, code codes.Code, message string) {
+	data, _ := json.Marshal(&errorBody{Code: code.String(), Message: message})
+	writeJson(w, httpStatus, data)
+}
+
+// get json value of string of url query or path var by kind of field. eg. true of bool, 100 of int32
+func getFieldValue(field protoreflect.FieldDescriptor, value string) (interface{}, error) {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: not a boolean \"%s\"", field.JSONName(), value)
+		}
+		return boolean, nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%s: not an integer \"%s\"", field.JSONName(), value)
+		}
+		return json.Number(value), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%s: not an unsigned integer \"%s\"", field.JSONName(), value)
+		}
+		return json.Number(value), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%s: not a number \"%s\"", field.JSONName(), value)
+		}
+		return json.Number(value), nil
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return nil, fmt.Errorf("%s: message is not supported in query, use json body", field.JSONName())
+	default:
+		// string, bytes and enum name
+		return value, nil
+	}
+}
+
+// get json value of values of url query or path var by field of key in message.
+// unknown key is left to protojson to be rejected
+func getQueryValue(message protoreflect.MessageDescriptor, key string, values []string) (interface{}, error) {
+	field := message.Fields().ByJSONName(key)
+	if field == nil {
+		field = message.Fields().ByName(protoreflect.Name(key))
+	}
+	if field == nil {
+		return values[0], nil
+	}
+
+	if !field.IsList() {
+		return getFieldValue(field, values[0])
+	}
+
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		item, err := getFieldValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+// decode query of json body, url query and path vars. path vars take precedence.
+// values of url query and path vars are converted by kind of field
+func decodeQuery(r *http.Request, query proto.Message) error {
+	fields := make(map[string]interface{})
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return fmt.Errorf("invalid json body: %v", err)
+		}
+	}
+
+	message := query.ProtoReflect().Descriptor()
+	for key, values := range r.URL.Query() {
+		if fields[key], err = getQueryValue(message, key, values); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range mux.Vars(r) {
+		if fields[key], err = getQueryValue(message, key, []string{value}); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+
+	return protojson.Unmarshal(data, query)
+}
+
+// get identity of http caller. common name of verified client certificate, or ip of caller.
+// loopback callers are not local, since any user of host may connect
+func getIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// get context of grpc call forwarding token or identity of http caller
+func getForwardContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx := r.Context()
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	} else {
+		ctx = httpConfig.AppendForwardedIdentity(ctx, getIdentity(r))
+	}
+
+	return context.WithTimeout(ctx, requestTimeout)
+}
+
+// handle request of route by grpc call
+func (rt *route) handle(w http.ResponseWriter, r *http.Request) {
+	query := rt.query.ProtoReflect().New().Interface()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	if err := decodeQuery(r, query); err != nil {
+		logger.Warn("%s %s: %v", r.Method, r.URL.Path, err)
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			writeError(w, http.StatusRequestEntityTooLarge, codes.InvalidArgument,
+				fmt.Sprintf("body is larger than %d bytes", maxBytesError.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, codes.InvalidArgument, err.Error())
+		return
+	}
+
+	ctx, cancel := getForwardContext(r)
+	defer cancel()
+
+	messages, err := rt.call(ctx, query)
+	if err != nil {
+		st := status.Convert(err)
+		logger.Warn("%s %s: %v", r.Method, r.URL.Path, err)
+		writeError(w, getHttpStatus(st.Code()), st.Code(), st.Message())
+		return
+	}
+	logger.Info("%s %s: %s", r.Method, r.URL.Path, rt.rpc)
+
+	marshaler := protojson.MarshalOptions{EmitUnpopulated: true}
+	if !rt.stream {
+		data, err := marshaler.Marshal(messages[0])
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codes.Internal, err.Error())
+			return
+		}
+		writeJson(w, rt.getStatus(), data)
+		return
+	}
+
+	items := make([]json.RawMessage, 0, len(messages))
+	for _, message := range messages {
+		data, err := marshaler.Marshal(message)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codes.Internal, err.Error())
+			return
+		}
+		items = append(items, data)
+	}
+	data, _ := json.Marshal(items)
+	writeJson(w, rt.getStatus(), data)
+}
+
+// get router of routes and openapi document
+func newRouter(routes []*route) *mux.Router {
+	router := mux.NewRouter()
+	for _, rt := range routes {
+		router.HandleFunc(rt.path, rt.handle).Methods(rt.method)
+	}
+
+	openApi, _ := json.MarshalIndent(newOpenApi(routes), "", "  ")
+	router.HandleFunc("/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, http.StatusOK, openApi)
+	}).Methods(http.MethodGet)
+
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, codes.NotFound, "no route of "+r.URL.Path)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, codes.Unimplemented, r.Method+" is not allowed on "+r.URL.Path)
+	})
+
+	return router
+}
+
+// serve rest api of networker and vmer on http port of config. https is served if tls is on
+func HttpServer(config *libutil.Config) {
+	httpConfig = config
+
+	var err error
+	logger, err = nblogger.NewLogger(config.GetLogPath("http.log"), nblogger.Info, 1000, nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
+	if err != nil {
+		log.Fatalf("logger init fail: %v", err)
+	}
+
+	address, err := config.GetHttpAddress()
+	if err != nil {
+		logger.Error("invalid http address: %v", err)
+		return
+	}
+
+	// token and identity of caller are forwarded instead of token of config
+	dialOption, err := config.GetDialOption()
+	if err != nil {
+		logger.Error("failed to load tls: %v", err)
+		return
+	}
+	netConn, err := grpc.Dial(libutil.GetDialTarget(config.GetNetAddress()), dialOption)
+	if err != nil {
+		logger.Error("did not connect: %v", err)
+		return
+	}
+	defer netConn.Close()
+	vmConn, err := grpc.Dial(libutil.GetDialTarget(config.GetVmAddress()), dialOption)
+	if err != nil {
+		logger.Error("did not connect: %v", err)
+		return
+	}
+	defer vmConn.Close()
+
+	routes := getRoutes(networker.NewNetworkerClient(netConn), vmer.NewVmerClient(vmConn))
+	newServer := &http.Server{
+		Addr:              address,
+		Handler:           newRouter(routes),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if config.Tls {
+		if newServer.TLSConfig, err = config.GetServerTlsConfig(); err != nil {
+			logger.Error("failed to load tls: %v", err)
+			return
+		}
+	}
+	httpServerMutex.Lock()
+	httpServer = newServer
+	httpServerMutex.Unlock()
+
+	logger.Info("http server listening at %v", address)
+	if config.Tls {
+		err = newServer.ListenAndServeTLS("", "")
+	} else {
+		err = newServer.ListenAndServe()
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("failed to serve: %v", err)
+	}
+}
+
+// stop HttpServer gracefully after pending requests are done
+func HttpServerStop() {
+	httpServerMutex.Lock()
+	stopServer := httpServer
+	httpServerMutex.Unlock()
+
+	if stopServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+		defer cancel()
+		stopServer.Shutdown(ctx)
+	}
+}
@@ -6,10 +6,68 @@ import (
 	"net"
 
 	"github.com/vishvananda/netlink"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// get netlink family of family name. both families if empty
+func getFamily(family string) (int, error) {
+	switch family {
+	case "":
+		return netlink.FAMILY_ALL, nil
+	case "inet":
+		return netlink.FAMILY_V4, nil
+	case "inet6":
+		return netlink.FAMILY_V6, nil
+	default:
+		return 0, status.Errorf(codes.InvalidArgument, "unknown family \"%s\"", family)
+	}
+}
+
+// get family name of netlink family
+func familyToString(family int) string {
+	if family == netlink.FAMILY_V6 {
+		return "inet6"
+	}
+	return "inet"
+}
+
+// get netlink family of ip
+func getIpFamily(ip net.IP) int {
+	if ip.To4() != nil {
+		return netlink.FAMILY_V4
+	}
+	return netlink.FAMILY_V6
+}
+
+// get families of family name. eg. inet, inet6 for empty
+func getFamilyList(family string) ([]int, error) {
+	nlFamily, err := getFamily(family)
+	if err != nil {
+		return nil, err
+	}
+
+	if nlFamily == netlink.FAMILY_ALL {
+		return []int{netlink.FAMILY_V4, netlink.FAMILY_V6}, nil
+	}
+	return []int{nlFamily}, nil
+}
+
 func (s *server) ShowAddr(ctx context.Context, in *networker.AddrQuery) (*networker.AddrResponse, error) {
-	linkList, err := netlink.LinkList()
+	family, err := getFamily(in.Family)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	linkList, err := handle.LinkList()
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
@@ -17,22 +75,20 @@ func (s *server) ShowAddr(ctx context.Context, in *networker.AddrQuery) (*networ
 
 	addrList := make([]*networker.Addr, 0)
 	for _, link := range linkList {
-		addrs, err := netlink.AddrList(link, 0)
+		addrs, err := handle.AddrList(link, family)
 		if err != nil {
 			logger.Warn("%v\n", err)
 			return nil, err
 		}
 
 		for _, addr := range addrs {
-			if len(addr.IP) == net.IPv6len {
-				continue
-			}
 			logger.Info("%s %v", link.Attrs().Name, addr)
 
 			if in.Name == "" || in.Name == link.Attrs().Name {
 				addrList = append(addrList, &networker.Addr{
 					Name:       link.Attrs().Name,
 					IpWithMask: addr.IPNet.String(),
+					Family:     familyToString(getIpFamily(addr.IP)),
 				})
 			}
 		}
@@ -41,46 +97,108 @@ func (s *server) ShowAddr(ctx context.Context, in *networker.AddrQuery) (*networ
 	return &networker.AddrResponse{Addrs: addrList}, err
 }
 
+// stage adding ip with mask to candidate
 func (s *server) AddAddr(ctx context.Context, in *networker.AddrQuery) (*networker.AddrResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
+	defer handle.Delete()
 
-	addr, err := netlink.ParseAddr(in.IpWithMask)
-	if err != nil {
+	if _, err := handle.LinkByName(in.Name); err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	err = netlink.AddrAdd(link, addr)
-	if err != nil {
+	if _, err := netlink.ParseAddr(in.IpWithMask); err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	return &networker.AddrResponse{}, err
+	s.stage(ctx, &networker.Change{Op: opAdd, Addr: in})
+	return &networker.AddrResponse{}, nil
 }
 
+// stage deleting ip with mask to candidate
 func (s *server) DelAddr(ctx context.Context, in *networker.AddrQuery) (*networker.AddrResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
+	defer handle.Delete()
+
+	if _, err := handle.LinkByName(in.Name); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	if _, err := netlink.ParseAddr(in.IpWithMask); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	s.stage(ctx, &networker.Change{Op: opDel, Addr: in})
+	return &networker.AddrResponse{}, nil
+}
+
+// add ip with mask to link
+func addAddr(in *networker.AddrQuery) error {
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
 
 	addr, err := netlink.ParseAddr(in.IpWithMask)
 	if err != nil {
 		logger.Warn("%v\n", err)
-		return nil, err
+		return err
 	}
 
-	err = netlink.AddrDel(link, addr)
+	err = handle.AddrAdd(link, addr)
 	if err != nil {
 		logger.Warn("%v\n", err)
-		return nil, err
+		return err
+	}
+
+	return nil
+}
+
+// delete ip with mask from link
+func delAddr(in *networker.AddrQuery) error {
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+
+	addr, err := netlink.ParseAddr(in.IpWithMask)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+
+	err = handle.AddrDel(link, addr)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
 	}
 
-	return &networker.AddrResponse{}, err
+	return nil
 }
@@ -0,0 +1,260 @@
+package libnet
+
+import (
+	"context"
+	"go-cli/pkg/libnet/networker"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// link monitoring interval in ms if miimon is unset
+const defaultMiimon = 100
+
+// get bond by name
+func getBond(name string) (*netlink.Bond, error) {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	bond, ok := link.(*netlink.Bond)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "link \"%s\" is not a bond", name)
+	}
+	return bond, nil
+}
+
+// get netlink bond by query
+func getNetlinkBond(in *networker.BondQuery) (*netlink.Bond, error) {
+	linkAttrs := netlink.NewLinkAttrs()
+	linkAttrs.Name = in.Name
+	bond := netlink.NewLinkBond(linkAttrs)
+
+	mode, ok := netlink.StringToBondModeMap[in.Mode]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown bond mode \"%s\"", in.Mode)
+	}
+	bond.Mode = mode
+
+	bond.Miimon = defaultMiimon
+	if in.Miimon != nil {
+		if *in.Miimon < 0 {
+			return nil, status.Error(codes.InvalidArgument, "miimon must not be negative")
+		}
+		bond.Miimon = int(*in.Miimon)
+	}
+
+	if in.LacpRate != "" {
+		if mode != netlink.BOND_MODE_802_3AD {
+			return nil, status.Error(codes.InvalidArgument, "lacp rate requires 802.3ad mode")
+		}
+		if bond.LacpRate, ok = netlink.StringToBondLacpRateMap[in.LacpRate]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown lacp rate \"%s\"", in.LacpRate)
+		}
+	}
+
+	if in.XmitHashPolicy != "" {
+		if bond.XmitHashPolicy, ok = netlink.StringToBondXmitHashPolicyMap[in.XmitHashPolicy]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown xmit hash policy \"%s\"", in.XmitHashPolicy)
+		}
+	}
+
+	return bond, nil
+}
+
+// list bonds with names of slaves
+func listBond() ([]*networker.Bond, error) {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	linkNames := make(map[int]string)
+	slaves := make(map[int][]string)
+	for _, link := range linkList {
+		linkNames[link.Attrs().Index] = link.Attrs().Name
+		if link.Attrs().MasterIndex != 0 {
+			slaves[link.Attrs().MasterIndex] = append(slaves[link.Attrs().MasterIndex], link.Attrs().Name)
+		}
+	}
+
+	bondList := make([]*networker.Bond, 0)
+	for _, link := range linkList {
+		bond, ok := link.(*netlink.Bond)
+		if !ok {
+			continue
+		}
+
+		bondList = append(bondList, &networker.Bond{
+			Name:           bond.Name,
+			Mode:           optionToString(bond.Mode, netlink.StringToBondModeMap),
+			Miimon:         int32(bond.Miimon),
+			LacpRate:       optionToString(bond.LacpRate, netlink.StringToBondLacpRateMap),
+			XmitHashPolicy: optionToString(bond.XmitHashPolicy, netlink.StringToBondXmitHashPolicyMap),
+			Mac:            bond.HardwareAddr.String(),
+			Status:         bond.OperState.String(),
+			ActiveSlave:    linkNames[bond.ActiveSlave],
+			Slaves:         slaves[bond.Index],
+		})
+	}
+
+	return bondList, nil
+}
+
+func (s *server) ShowBond(ctx context.Context, in *networker.BondQuery) (*networker.BondResponse, error) {
+	bondList, err := listBond()
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	if in.Name != "" {
+		for _, bond := range bondList {
+			if bond.Name == in.Name {
+				return &networker.BondResponse{Bonds: []*networker.Bond{bond}}, nil
+			}
+		}
+		return &networker.BondResponse{}, nil
+	}
+
+	return &networker.BondResponse{Bonds: bondList}, nil
+}
+
+// show state of slaves of bond. slaves of all bonds if name is empty
+func (s *server) ShowBondSlave(ctx context.Context, in *networker.BondQuery) (*networker.BondSlaveResponse, error) {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	linkNames := make(map[int]string)
+	for _, link := range linkList {
+		linkNames[link.Attrs().Index] = link.Attrs().Name
+	}
+
+	slaveList := make([]*networker.BondSlave, 0)
+	for _, link := range linkList {
+		slave, ok := link.Attrs().Slave.(*netlink.BondSlave)
+		if !ok {
+			continue
+		}
+
+		master := linkNames[link.Attrs().MasterIndex]
+		if in.Name != "" && in.Name != master {
+			continue
+		}
+
+		slaveList = append(slaveList, &networker.BondSlave{
+			Name:             link.Attrs().Name,
+			Master:           master,
+			Status:           link.Attrs().OperState.String(),
+			State:            slave.State.String(),
+			MiiStatus:        slave.MiiStatus.String(),
+			LinkFailureCount: slave.LinkFailureCount,
+			PermMac:          slave.PermHardwareAddr.String(),
+			AggregatorId:     int32(slave.AggregatorId),
+		})
+	}
+
+	return &networker.BondSlaveResponse{Slaves: slaveList}, nil
+}
+
+func (s *server) AddBond(ctx context.Context, in *networker.BondQuery) (*networker.BondResponse, error) {
+	newBond, err := getNetlinkBond(in)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.LinkAdd(newBond)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	link, err := netlink.LinkByName(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	netlink.LinkSetUp(link)
+
+	return s.ShowBond(ctx, &networker.BondQuery{Name: in.Name})
+}
+
+func (s *server) DelBond(ctx context.Context, in *networker.BondQuery) (*networker.BondResponse, error) {
+	bond, err := getBond(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.LinkDel(bond)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.BondResponse{}, nil
+}
+
+// enslave link to bond. link must be down to be enslaved,
+// and is brought back to its previous state if it fails
+func (s *server) SetBondMaster(ctx context.Context, in *networker.BondQuery) (*networker.BondResponse, error) {
+	bond, err := getBond(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	slave, err := netlink.LinkByName(in.SlaveName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	isUp := slave.Attrs().Flags&net.FlagUp != 0
+	netlink.LinkSetDown(slave)
+	err = netlink.LinkSetMaster(slave, bond)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		if isUp {
+			netlink.LinkSetUp(slave)
+		}
+		return nil, err
+	}
+	netlink.LinkSetUp(slave)
+
+	return &networker.BondResponse{}, nil
+}
+
+// release slave from bond
+func (s *server) UnsetBondMaster(ctx context.Context, in *networker.BondQuery) (*networker.BondResponse, error) {
+	bond, err := getBond(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	slave, err := netlink.LinkByName(in.SlaveName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	if slave.Attrs().MasterIndex != bond.Index {
+		err = status.Errorf(codes.InvalidArgument, "link \"%s\" is not a slave of \"%s\"", in.SlaveName, in.Name)
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.LinkSetNoMaster(slave)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.BondResponse{}, nil
+}
@@ -0,0 +1,264 @@
+package libnet
+
+import (
+	"context"
+	"fmt"
+	"go-cli/pkg/libnet/networker"
+	"go-cli/pkg/libutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+const (
+	opAdd = "add"
+	opDel = "del"
+)
+
+// stage change to candidate. change is audited as staged, and as applied on commit
+func (s *server) stage(ctx context.Context, change *networker.Change) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.changes = append(s.changes, change)
+	logger.Info("stage %v", change)
+	libutil.SetAuditResult(ctx, "staged")
+}
+
+// get type and config of change. eg. route, 10.0.0.0/8 via 10.0.0.1 table main
+func getChangeConfig(change *networker.Change) (string, string) {
+	switch {
+	case change.Addr != nil:
+		str := fmt.Sprintf("%s name %s", change.Addr.IpWithMask, change.Addr.Name)
+		if change.Addr.Netns != "" {
+			str += fmt.Sprintf(" netns %s", change.Addr.Netns)
+		}
+		return "addr", str
+	case change.Route != nil:
+		route := change.Route
+		str := route.Destination
+		if route.Source != "" {
+			str += fmt.Sprintf(" src %s", route.Source)
+		}
+		if route.NextHop != "" {
+			str += fmt.Sprintf(" via %s", route.NextHop)
+		}
+		if route.Device != "" {
+			str += fmt.Sprintf(" dev %s", route.Device)
+		}
+		if route.Table != "" {
+			str += fmt.Sprintf(" table %s", route.Table)
+		}
+		if route.Netns != "" {
+			str += fmt.Sprintf(" netns %s", route.Netns)
+		}
+		return "route", str
+	case change.Rule != nil:
+		rule := change.Rule
+		str := fmt.Sprintf("table %s priority %d src %s dst %s s-port %s d-port %s proto %s",
+			rule.Table, rule.Priority, rule.Src, rule.Dst, rule.SPort, rule.DPort, rule.IpProto)
+		if rule.Family != "" {
+			str += fmt.Sprintf(" family %s", rule.Family)
+		}
+		if rule.Netns != "" {
+			str += fmt.Sprintf(" netns %s", rule.Netns)
+		}
+		return "rule", str
+	default:
+		return "", ""
+	}
+}
+
+// get string of change. eg. add route 10.0.0.0/8 via 10.0.0.1 table main
+func changeToString(change *networker.Change) string {
+	changeType, config := getChangeConfig(change)
+	if changeType == "" {
+		return change.Op
+	}
+	return fmt.Sprintf("%s %s %s", change.Op, changeType, config)
+}
+
+// get string of changes for audit log. eg. add route 10.0.0.0/8 table main; del addr ...
+func changesToString(changes []*networker.Change) string {
+	if len(changes) == 0 {
+		return "no changes"
+	}
+
+	strs := make([]string, 0, len(changes))
+	for _, change := range changes {
+		strs = append(strs, changeToString(change))
+	}
+	return strings.Join(strs, "; ")
+}
+
+// apply change. undo func reverts the change
+func applyChange(change *networker.Change) (func() error, error) {
+	isAdd := change.Op == opAdd
+
+	switch {
+	case change.Addr != nil:
+		addr := change.Addr
+		if isAdd {
+			return func() error { return delAddr(addr) }, addAddr(addr)
+		}
+		return func() error { return addAddr(addr) }, delAddr(addr)
+	case change.Route != nil:
+		route := change.Route
+		if isAdd {
+			added, err := addRoute(route)
+			return func() error { return delNetlinkRoute(route.Netns, added) }, err
+		}
+
+		// deleted routes are added back as they were, not by query
+		deleted, err := delRoute(route)
+		return func() error { return addNetlinkRoutes(route.Netns, deleted) }, err
+	case change.Rule != nil:
+		rule := change.Rule
+		if isAdd {
+			added, err := addRule(rule)
+			return func() error { return delNetlinkRule(rule.Netns, added) }, err
+		}
+
+		deleted, err := delRule(rule)
+		undo := func() error {
+			handle, err := getHandle(rule.Netns)
+			if err != nil {
+				logger.Warn("%v\n", err)
+				return err
+			}
+			defer handle.Delete()
+
+			for i := range deleted {
+				if err := handle.RuleAdd(&deleted[i]); err != nil {
+					logger.Warn("%v\n", err)
+					return err
+				}
+			}
+			return nil
+		}
+		return undo, err
+	default:
+		return nil, fmt.Errorf("unknown change %v", change)
+	}
+}
+
+// run undo funcs in reverse order
+func rollback(undoSlice []func() error) {
+	for i := len(undoSlice) - 1; i >= 0; i-- {
+		if err := undoSlice[i](); err != nil {
+			logger.Warn("failed to rollback: %v", err)
+		}
+	}
+}
+
+// confirm pending commit. must be called with lock
+func (s *server) confirm() bool {
+	if s.confirmTimer == nil {
+		return false
+	}
+
+	s.confirmTimer.Stop()
+	s.confirmTimer = nil
+	s.confirmUndo = nil
+	s.confirmNumber++
+	return true
+}
+
+// show staged changes
+func (s *server) ShowCandidate(ctx context.Context, in *networker.CandidateQuery) (*networker.CandidateResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return &networker.CandidateResponse{Changes: s.changes}, nil
+}
+
+// discard staged changes
+func (s *server) DiscardCandidate(ctx context.Context, in *networker.CandidateQuery) (*networker.CandidateResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	changes := s.changes
+	s.changes = nil
+	libutil.SetAuditResult(ctx, "discarded: "+changesToString(changes))
+	return &networker.CandidateResponse{Changes: changes}, nil
+}
+
+// apply staged changes atomically. applied changes are rolled back if a change
+// fails. if confirm seconds is set, changes are rolled back unless confirmed
+func (s *server) Commit(ctx context.Context, in *networker.CommitQuery) (*networker.CandidateResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// commit confirms pending commit
+	s.confirm()
+
+	undoSlice := make([]func() error, 0)
+	for _, change := range s.changes {
+		undo, err := applyChange(change)
+		if err != nil {
+			// partially applied rule or route del is reverted by its undo
+			if undo != nil && (change.Rule != nil || change.Route != nil) && change.Op == opDel {
+				undoSlice = append(undoSlice, undo)
+			}
+			rollback(undoSlice)
+			st := status.Convert(libutil.ToStatusError(err, getErrorCode))
+			return nil, status.Errorf(st.Code(), "failed to %s: %s, rolled back", changeToString(change), st.Message())
+		}
+		undoSlice = append(undoSlice, undo)
+	}
+
+	changes := s.changes
+	s.changes = nil
+	result := "applied: " + changesToString(changes)
+
+	if in.ConfirmSeconds > 0 {
+		result = fmt.Sprintf("applied, rolled back unless confirmed in %ds: %s",
+			in.ConfirmSeconds, changesToString(changes))
+
+		identity := libutil.GetIdentity(ctx)
+		number := s.confirmNumber
+		s.confirmUndo = undoSlice
+		s.confirmTimer = time.AfterFunc(time.Duration(in.ConfirmSeconds)*time.Second, func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+
+			// confirmed or committed again
+			if number != s.confirmNumber {
+				return
+			}
+
+			logger.Warn("commit is not confirmed, rollback")
+			rollback(s.confirmUndo)
+			if auditDB != nil {
+				if err := auditDB.Insert(&libutil.AuditLog{
+					Identity: identity,
+					Service:  networker.Networker_ServiceDesc.ServiceName,
+					Method:   "Commit",
+					Resource: "commit",
+					Result:   "not confirmed, rolled back: " + changesToString(changes),
+				}); err != nil {
+					logger.Warn("failed to insert audit log: %v", err)
+				}
+			}
+			s.confirmUndo = nil
+			s.confirmTimer = nil
+			s.confirmNumber++
+		})
+	}
+
+	libutil.SetAuditResult(ctx, result)
+	return &networker.CandidateResponse{Changes: changes}, nil
+}
+
+// confirm pending commit
+func (s *server) ConfirmCommit(ctx context.Context, in *networker.CommitQuery) (*networker.CandidateResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.confirm() {
+		return nil, status.Error(codes.FailedPrecondition, "no commit to confirm")
+	}
+
+	return &networker.CandidateResponse{}, nil
+}
@@ -6,55 +6,107 @@ import (
 	"go-cli/pkg/libcli"
 	"go-cli/pkg/libnet/networker"
 	"go-cli/pkg/libutil"
-	"log"
+	"math"
 	"strconv"
+	"sync"
 	"time"
 
+	nblogger "github.com/banaconda/nb-logger"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// connection is shared by clis of ssh sessions
 var conn *grpc.ClientConn
-var client networker.NetworkerClient
+var connErr error
+var connOnce sync.Once
+
+// logger of clients. logger of server is not set when cli runs without daemon
+var cliLogger nblogger.Logger
 var nce = libcli.NewCommandElemWithoutFunc
 var ncef = libcli.NewCommandElem
+var nca = libcli.NewArgElemWithoutFunc
+var ncaf = libcli.NewArgElem
+var ncep = libcli.NewCommandElemWithParams
+var nceap = libcli.NewCommandElemWithArgsParams
+var ncap = libcli.NewArgElemWithParams
+var np = libcli.NewParam
 
-func InitCli(cli *libcli.GoCli) {
-	var err error
-	conn, err = grpc.Dial(fmt.Sprintf("%s:%d", "localhost", libutil.NET_PORT),
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+// kinds of bond options
+var kindBondMode = libcli.KindEnum("balance-rr", "active-backup", "balance-xor", "broadcast", "802.3ad",
+	"balance-tlb", "balance-alb")
+var kindBondLacpRate = libcli.KindEnum("slow", "fast")
+var kindBondXmitHashPolicy = libcli.KindEnum("layer2", "layer2+3", "layer3+4", "encap2+3", "encap3+4")
+var kindOnOff = libcli.KindEnum("on", "off")
+
+// kinds of modes of sub interfaces
+var kindMacvlanMode = libcli.KindEnum("bridge", "private", "vepa", "passthru")
+var kindIpvlanMode = libcli.KindEnum("l2", "l3", "l3s")
+var kindTuntapMode = libcli.KindEnum("tun", "tap")
+
+// init cli of networker at grpc target. eg. unix:/run/go-cli/net.sock
+func InitCli(cli *libcli.GoCli, config *libutil.Config, identity string, clientLogger nblogger.Logger) error {
+	connOnce.Do(func() {
+		cliLogger = clientLogger
+		dialOptions, err := config.GetDialOptions()
+		if err != nil {
+			connErr = fmt.Errorf("failed to load tls: %v", err)
+			return
+		}
+		conn, connErr = grpc.Dial(libutil.GetDialTarget(config.GetNetAddress()), dialOptions...)
+	})
+	if connErr != nil {
+		return connErr
 	}
-	client = networker.NewNetworkerClient(conn)
 
-	initCliLink(cli)
-	initCliAddr(cli)
-	initCliRule(cli)
-	initCliRoute(cli)
+	// identity of caller is forwarded if set. eg. user of ssh session
+	client := networker.NewNetworkerClient(config.ForwardIdentity(conn, identity))
+
+	initCliLink(cli, client)
+	initCliBond(cli, client)
+	initCliVxlan(cli, client)
+	initCliMacvlan(cli, client)
+	initCliTuntap(cli, client)
+	initCliNetns(cli, client)
+	initCliAddr(cli, client)
+	initCliRule(cli, client)
+	initCliRoute(cli, client)
+	initCliCandidate(cli, client)
+
+	return nil
 }
 
 type networkerQuery interface {
 	// LINK
-	*networker.NetLinkQuery | *networker.BridgeQuery |
+	*networker.NetLinkQuery | *networker.BridgeQuery | *networker.BondQuery |
+		*networker.VxlanQuery | *networker.FdbQuery |
+		*networker.MacvlanQuery | *networker.IpvlanQuery | *networker.TuntapQuery |
 		*networker.VethQuery | *networker.VlanQuery |
+		// NETNS
+		*networker.NetnsQuery |
 		// ADDR
 		*networker.AddrQuery |
 		// RULE
 		*networker.RuleQuery |
 		// ROUTE
-		*networker.RouteQuery
+		*networker.RouteQuery |
+		// CANDIDATE
+		*networker.CandidateQuery | *networker.CommitQuery
 }
 
 type networkerReponse interface {
 	// LINK
-	*networker.NetLinkResponse |
+	*networker.NetLinkResponse | *networker.BondResponse | *networker.BondSlaveResponse |
+		*networker.VxlanResponse | *networker.FdbResponse | *networker.TuntapResponse |
+		// NETNS
+		*networker.NetnsResponse |
 		// ADDR
 		*networker.AddrResponse |
 		// RULE
 		*networker.RuleResponse |
 		// ROUTE
-		*networker.RouteResponse
+		*networker.RouteResponse |
+		// CANDIDATE
+		*networker.CandidateResponse
 }
 
 // networkerQuery interface
@@ -65,825 +117,1275 @@ func query[Q networkerQuery, R networkerReponse](f queryInterface[Q, R], queryEl
 	defer cancel()
 	r, err := f(ctx, queryElem)
 	if err != nil {
-		logger.Warn("%v", err)
+		cliLogger.Warn("%v", err)
 	} else {
-		logger.Info("%v", r)
+		cliLogger.Info("%v", r)
 	}
 
 	return r, err
 }
 
-func initCliLink(cli *libcli.GoCli) {
-	// show all links
+// get link by name in netns. netns of daemon if netns is empty
+func getLink(client networker.NetworkerClient, name string, netns string) (*networker.NetLink, error) {
+	resp, err := query(client.ShowNetLink, &networker.NetLinkQuery{Netns: netns})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, link := range resp.NetLinks {
+		if link.Name == name {
+			return link, nil
+		}
+	}
+
+	return nil, fmt.Errorf("link \"%s\" does not exist", name)
+}
+
+// complete link names by link type. all links are listed if link type is empty.
+// links of netns are listed if netns param is typed before
+func completeLink(client networker.NetworkerClient, linkType string) func(args []string) []string {
+	return func(args []string) []string {
+		resp, err := query(client.ShowNetLink, &networker.NetLinkQuery{Netns: getParamValue(args, "netns")})
+		if err != nil {
+			return nil
+		}
+
+		names := make([]string, 0)
+		for _, link := range resp.NetLinks {
+			if linkType == "" || linkType == link.Type {
+				names = append(names, link.Name)
+			}
+		}
+		return names
+	}
+}
+
+// complete names of netns
+func completeNetns(client networker.NetworkerClient) func(args []string) []string {
+	return func(args []string) []string {
+		resp, err := query(client.ShowNetns, &networker.NetnsQuery{})
+		if err != nil {
+			return nil
+		}
+
+		names := make([]string, 0)
+		for _, netns := range resp.Netns {
+			names = append(names, netns.Name)
+		}
+		return names
+	}
+}
+
+// get value of param typed in args. eg. red of # netns set name red. empty if not typed
+func getParamValue(args []string, name string) string {
+	for i := 1; i+1 < len(args); i++ {
+		if args[i] == name {
+			return args[i+1]
+		}
+	}
+
+	return ""
+}
+
+// complete link names of netns named by name param
+func completeNetnsLink(client networker.NetworkerClient) func(args []string) []string {
+	return func(args []string) []string {
+		netns := getParamValue(args, "name")
+		if netns == "" {
+			return nil
+		}
+
+		resp, err := query(client.ShowNetLink, &networker.NetLinkQuery{Netns: netns})
+		if err != nil {
+			return nil
+		}
+
+		names := make([]string, 0)
+		for _, link := range resp.NetLinks {
+			names = append(names, link.Name)
+		}
+		return names
+	}
+}
+
+// complete tables in use by rules
+func completeRuleTable(client networker.NetworkerClient) func(args []string) []string {
+	return func(args []string) []string {
+		resp, err := query(client.ShowRule, &networker.RuleQuery{})
+		if err != nil {
+			return nil
+		}
+
+		tableMap := make(map[string]bool)
+		for _, rule := range resp.Rules {
+			tableMap[rule.Table] = true
+		}
+
+		tables := make([]string, 0)
+		for table := range tableMap {
+			tables = append(tables, table)
+		}
+		return tables
+	}
+}
+
+// complete tables in use by routes
+func completeRouteTable(client networker.NetworkerClient) func(args []string) []string {
+	return func(args []string) []string {
+		resp, err := query(client.ShowRoute, &networker.RouteQuery{})
+		if err != nil {
+			return nil
+		}
+
+		tableMap := make(map[string]bool)
+		for _, route := range resp.Routes {
+			tableMap[route.Table] = true
+		}
+
+		tables := make([]string, 0)
+		for table := range tableMap {
+			tables = append(tables, table)
+		}
+		return tables
+	}
+}
+
+func initCliLink(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show links of netns or of host
 	cli.AddCommandElem(
 		nce("link", ""),
-		ncef("show", "show all links", func(args []string) {
-			resp, err := query(client.ShowNetLink, &networker.NetLinkQuery{})
+		ncep("show", "show links", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowNetLink, &networker.NetLinkQuery{
+				Netns: params["netns"],
+			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// set link mac by link name
 	cli.AddCommandElem(
 		nce("link", ""),
 		nce("name", ""),
-		nce(libutil.NameRegex, "link name"),
+		nca(libcli.KindName, "link name").SetCompleter(completeLink(client, "")),
 		nce("mac", ""),
 		nce("set", "set link mac by link name"),
-		ncef(libutil.MacRegex, "mac address", func(args []string) {
+		ncap(libcli.KindMac, "mac address", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(args []string, params map[string]string) error {
 			resp, err := query(client.SetNetLinkMac, &networker.NetLinkQuery{
-				Name: args[2],
-				Mac:  args[5],
+				Name:  args[2],
+				Mac:   args[5],
+				Netns: params["netns"],
 			})
 
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// set link up by link name
 	cli.AddCommandElem(
 		nce("link", ""),
 		nce("name", ""),
-		nce(libutil.NameRegex, "link name"),
-		ncef("up", "", func(args []string) {
+		nca(libcli.KindName, "link name").SetCompleter(completeLink(client, "")),
+		nceap("up", "set link up", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(args []string, params map[string]string) error {
 			resp, err := query(client.SetNetLinkUp, &networker.NetLinkQuery{
-				Name: args[2],
+				Name:  args[2],
+				Netns: params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// set link down by link name
 	cli.AddCommandElem(
 		nce("link", ""),
 		nce("name", ""),
-		nce(libutil.NameRegex, "link name"),
-		ncef("down", "", func(args []string) {
+		nca(libcli.KindName, "link name").SetCompleter(completeLink(client, "")),
+		nceap("down", "set link down", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(args []string, params map[string]string) error {
 			resp, err := query(client.SetNetLinkDown, &networker.NetLinkQuery{
-				Name: args[2],
+				Name:  args[2],
+				Netns: params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
-	// show all bridges
+	// enter link mode. eg. # link name eth0 → (link:eth0)#
+	cli.AddCommandElem(
+		nce("link", ""),
+		nce("name", ""),
+		ncaf(libcli.KindName, "link name", func(args []string) error {
+			_, err := getLink(client, args[2], "")
+			return err
+		}).SetMode("link").SetCompleter(completeLink(client, "")))
+
+	// show link in link mode
+	cli.AddCommandElem(
+		nce("link", ""),
+		nce("name", ""),
+		nca(libcli.KindName, "link name"),
+		nceap("show", "show link", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(args []string, params map[string]string) error {
+			link, err := getLink(client, args[2], params["netns"])
+			if err != nil {
+				return err
+			}
+			cli.PrintStructOne(link)
+			return nil
+		}))
+
+	// show addresses in link mode
+	cli.AddCommandElem(
+		nce("link", ""),
+		nce("name", ""),
+		nca(libcli.KindName, "link name"),
+		nce("addr", ""),
+		nceap("show", "show addresses of link", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(args []string, params map[string]string) error {
+			resp, err := query(client.ShowAddr, &networker.AddrQuery{
+				Name:  args[2],
+				Netns: params["netns"],
+			})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.Addrs)
+			return nil
+		}))
+
+	// add ip with mask in link mode
+	cli.AddCommandElem(
+		nce("link", ""),
+		nce("name", ""),
+		nca(libcli.KindName, "link name"),
+		nce("addr", ""),
+		nce("add", ""),
+		ncap(libcli.KindCidr, "ip with mask", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(args []string, params map[string]string) error {
+			resp, err := query(client.AddAddr, &networker.AddrQuery{
+				Name:       args[2],
+				IpWithMask: args[5],
+				Netns:      params["netns"],
+			})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.Addrs)
+			return nil
+		}))
+
+	// del ip with mask in link mode
+	cli.AddCommandElem(
+		nce("link", ""),
+		nce("name", ""),
+		nca(libcli.KindName, "link name"),
+		nce("addr", ""),
+		nce("del", ""),
+		ncap(libcli.KindCidr, "ip with mask", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(args []string, params map[string]string) error {
+			resp, err := query(client.DelAddr, &networker.AddrQuery{
+				Name:       args[2],
+				IpWithMask: args[5],
+				Netns:      params["netns"],
+			})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.Addrs)
+			return nil
+		}))
+
+	// show bridges of netns or of host
 	cli.AddCommandElem(
 		nce("bridge", ""),
-		ncef("show", "show all bridges", func(args []string) {
-			resp, err := query(client.ShowBridge, &networker.BridgeQuery{})
+		ncep("show", "show bridges", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowBridge, &networker.BridgeQuery{
+				Netns: params["netns"],
+			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// show bridge slaves by bridge name
 	cli.AddCommandElem(
 		nce("bridge", ""),
-		nce("show", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "bridge name"),
-		ncef("slave", "show bridge slaves by bridge name", func(args []string) {
+		nce("slave", ""),
+		ncep("show", "show bridge slaves by bridge name", []*libcli.Param{
+			np("name", libcli.KindName, "bridge name").SetRequired().SetCompleter(completeLink(client, "bridge")),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.ShowBridgeSlave, &networker.BridgeQuery{
-				Name: args[3],
+				Name:  params["name"],
+				Netns: params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// set bridge master by bridge name and slave name
 	cli.AddCommandElem(
 		nce("bridge", ""),
-		nce("set", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "bridge name"),
-		nce("slave", ""),
-		ncef(libutil.NameRegex, "slave name", func(args []string) {
+		ncep("set", "set bridge master of slave", []*libcli.Param{
+			np("name", libcli.KindName, "bridge name").SetRequired().SetCompleter(completeLink(client, "bridge")),
+			np("slave", libcli.KindName, "slave name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.SetBridgeMaster, &networker.BridgeQuery{
-				Name:      args[3],
-				SlaveName: args[5],
+				Name:      params["name"],
+				SlaveName: params["slave"],
+				Netns:     params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// unset bridge master by bridge name and slave name
 	cli.AddCommandElem(
 		nce("bridge", ""),
-		nce("unset", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "bridge name"),
-		nce("slave", ""),
-		ncef(libutil.NameRegex, "slave name", func(args []string) {
+		ncep("unset", "unset bridge master of slave", []*libcli.Param{
+			np("name", libcli.KindName, "bridge name").SetRequired().SetCompleter(completeLink(client, "bridge")),
+			np("slave", libcli.KindName, "slave name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.UnsetBridgeMaster, &networker.BridgeQuery{
-				Name:      args[3],
-				SlaveName: args[5],
+				Name:      params["name"],
+				SlaveName: params["slave"],
+				Netns:     params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// add bridge by bridge name
 	cli.AddCommandElem(
 		nce("bridge", ""),
-		nce("add", ""),
-		nce("name", ""),
-		ncef(libutil.NameRegex, "bridge name", func(args []string) {
+		ncep("add", "add bridge", []*libcli.Param{
+			np("name", libcli.KindName, "bridge name").SetRequired(),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.AddBridge, &networker.BridgeQuery{
-				Name: args[3],
+				Name:  params["name"],
+				Netns: params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// del bridge by bridge name
 	cli.AddCommandElem(
 		nce("bridge", ""),
-		nce("del", ""),
-		nce("name", ""),
-		ncef(libutil.NameRegex, "bridge name", func(args []string) {
+		ncep("del", "delete bridge", []*libcli.Param{
+			np("name", libcli.KindName, "bridge name").SetRequired().SetCompleter(completeLink(client, "bridge")),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.DelBridge, &networker.BridgeQuery{
-				Name: args[3],
+				Name:  params["name"],
+				Netns: params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
-	// show all veths
+	// show veths of netns or of host
 	cli.AddCommandElem(
 		nce("veth", ""),
-		ncef("show", "show all veths", func(args []string) {
-			resp, err := query(client.ShowVeth, &networker.VethQuery{})
+		ncep("show", "show veths", []*libcli.Param{
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowVeth, &networker.VethQuery{
+				Netns: params["netns"],
+			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
-	// add veth by veth name and peer name
+	// add veth by veth name and peer name in any order. peer is moved into netns of peer-netns,
+	// or created in netns of veth if peer-netns is not set
 	cli.AddCommandElem(
 		nce("veth", ""),
-		nce("add", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "veth name"),
-		nce("peer", ""),
-		nce("name", ""),
-		ncef(libutil.NameRegex, "peer name", func(args []string) {
+		ncep("add", "add veth", []*libcli.Param{
+			np("name", libcli.KindName, "veth name").SetRequired(),
+			np("peer", libcli.KindName, "peer name").SetRequired(),
+			np("peer-netns", libcli.KindName, "netns of peer").SetCompleter(completeNetns(client)),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.AddVeth, &networker.VethQuery{
-				Name:     args[3],
-				PeerName: args[6],
+				Name:      params["name"],
+				PeerName:  params["peer"],
+				PeerNetns: params["peer-netns"],
+				Netns:     params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
+
 	// del veth by veth name
 	cli.AddCommandElem(
 		nce("veth", ""),
-		nce("del", ""),
-		nce("name", ""),
-		ncef(libutil.NameRegex, "veth name", func(args []string) {
+		ncep("del", "delete veth", []*libcli.Param{
+			np("name", libcli.KindName, "veth name").SetRequired().SetCompleter(completeLink(client, "veth")),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.DelVeth, &networker.VethQuery{
-				Name: args[3],
+				Name:  params["name"],
+				Netns: params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
-	// show all vlans
+	// show vlans filtered by name and vlan id
 	cli.AddCommandElem(
 		nce("vlan", ""),
-		ncef("show", "show all vlans", func(args []string) {
-			resp, err := query(client.ShowVlan, &networker.VlanQuery{})
-			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
-			}
-			libutil.PrintStructAll(resp.NetLinks)
-		}))
-
-	// show vlan by vlan id
-	cli.AddCommandElem(
-		nce("vlan", ""),
-		nce("show", ""),
-		nce("id", ""),
-		ncef(libutil.NumberRegex, "show vlan by vlan id", func(args []string) {
-			vlanId, _ := strconv.Atoi(args[3])
+		ncep("show", "show vlans", []*libcli.Param{
+			np("name", libcli.KindName, "vlan name").SetCompleter(completeLink(client, "vlan")),
+			np("id", libcli.KindInt(1, 4094), "vlan id"),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			vlanId, _ := strconv.Atoi(params["id"])
 			resp, err := query(client.ShowVlan, &networker.VlanQuery{
+				Name:   params["name"],
 				VlanId: int32(vlanId),
+				Netns:  params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
-	// add vlan by vlan id and parent name
+	// add vlan by vlan name, parent name and vlan id in any order
 	cli.AddCommandElem(
 		nce("vlan", ""),
-		nce("add", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "vlan name"),
-		nce("parent", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "parent name"),
-		nce("id", ""),
-		ncef(libutil.NumberRegex, "vlan id", func(args []string) {
-			vlanId, _ := strconv.Atoi(args[8])
+		ncep("add", "add vlan", []*libcli.Param{
+			np("name", libcli.KindName, "vlan name").SetRequired(),
+			np("parent", libcli.KindName, "parent name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("id", libcli.KindInt(1, 4094), "vlan id").SetRequired(),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			vlanId, _ := strconv.Atoi(params["id"])
 			resp, err := query(client.AddVlan, &networker.VlanQuery{
-				Name:       args[3],
-				ParentName: args[6],
+				Name:       params["name"],
+				ParentName: params["parent"],
 				VlanId:     int32(vlanId),
+				Netns:      params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 
 	// del vlan by vlan name
 	cli.AddCommandElem(
 		nce("vlan", ""),
-		nce("del", ""),
-		nce("name", ""),
-		ncef(libutil.NameRegex, "vlan name", func(args []string) {
+		ncep("del", "delete vlan", []*libcli.Param{
+			np("name", libcli.KindName, "vlan name").SetRequired().SetCompleter(completeLink(client, "vlan")),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.DelVlan, &networker.VlanQuery{
-				Name: args[3],
+				Name:  params["name"],
+				Netns: params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.NetLinks)
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
 		}))
 }
 
-func initCliAddr(cli *libcli.GoCli) {
-	// show all addresses
+func initCliBond(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show bonds. bond of name if name is set
 	cli.AddCommandElem(
-		nce("addr", ""),
-		ncef("show", "show all addresses", func(args []string) {
-			resp, err := query(client.ShowAddr, &networker.AddrQuery{})
+		nce("bond", ""),
+		ncep("show", "show bonds", []*libcli.Param{
+			np("name", libcli.KindName, "bond name").SetCompleter(completeLink(client, "bond")),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowBond, &networker.BondQuery{
+				Name: params["name"],
+			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Addrs)
+			cli.PrintStructAll(resp.Bonds)
+			return nil
 		}))
 
-	// show address by name
+	// show state of slaves. slaves of bond of name if name is set
 	cli.AddCommandElem(
-		nce("addr", ""),
-		nce("show", ""),
-		nce("name", ""),
-		ncef(libutil.NameRegex, "address name", func(args []string) {
-			resp, err := query(client.ShowAddr, &networker.AddrQuery{
-				Name: args[3],
+		nce("bond", ""),
+		nce("slave", ""),
+		ncep("show", "show state of bond slaves", []*libcli.Param{
+			np("name", libcli.KindName, "bond name").SetCompleter(completeLink(client, "bond")),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowBondSlave, &networker.BondQuery{
+				Name: params["name"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Addrs)
+			cli.PrintStructAll(resp.Slaves)
+			return nil
 		}))
 
-	// add ip with mask by name
+	// add bond by name, mode and options in any order
 	cli.AddCommandElem(
-		nce("addr", ""),
-		nce("add", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "address name"),
-		nce("ipWithMask", ""),
-		ncef(libutil.CidrRegex, "ip with mask", func(args []string) {
-			resp, err := query(client.AddAddr, &networker.AddrQuery{
-				Name:       args[3],
-				IpWithMask: args[5],
+		nce("bond", ""),
+		ncep("add", "add bond", []*libcli.Param{
+			np("name", libcli.KindName, "bond name").SetRequired(),
+			np("mode", kindBondMode, "bond mode").SetRequired(),
+			np("miimon", libcli.KindInt(0, math.MaxInt32), "link monitoring interval in ms").SetDefault("100"),
+			np("lacp-rate", kindBondLacpRate, "lacp rate of 802.3ad"),
+			np("xmit-hash-policy", kindBondXmitHashPolicy, "transmit hash policy"),
+		}, func(params map[string]string) error {
+			miimon, _ := strconv.Atoi(params["miimon"])
+			miimon32 := int32(miimon)
+			resp, err := query(client.AddBond, &networker.BondQuery{
+				Name:           params["name"],
+				Mode:           params["mode"],
+				Miimon:         &miimon32,
+				LacpRate:       params["lacp-rate"],
+				XmitHashPolicy: params["xmit-hash-policy"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Addrs)
+			cli.PrintStructAll(resp.Bonds)
+			return nil
 		}))
 
-	// del ip with mask by name
+	// del bond by bond name
 	cli.AddCommandElem(
-		nce("addr", ""),
-		nce("del", ""),
-		nce("name", ""),
-		nce(libutil.NameRegex, "address name"),
-		nce("ipWithMask", ""),
-		ncef(libutil.CidrRegex, "ip with mask", func(args []string) {
-			resp, err := query(client.DelAddr, &networker.AddrQuery{
-				Name:       args[3],
-				IpWithMask: args[5],
+		nce("bond", ""),
+		ncep("del", "delete bond", []*libcli.Param{
+			np("name", libcli.KindName, "bond name").SetRequired().SetCompleter(completeLink(client, "bond")),
+		}, func(params map[string]string) error {
+			_, err := query(client.DelBond, &networker.BondQuery{
+				Name: params["name"],
+			})
+			return err
+		}))
+
+	// enslave link to bond by bond name and slave name
+	cli.AddCommandElem(
+		nce("bond", ""),
+		ncep("enslave", "enslave link to bond", []*libcli.Param{
+			np("name", libcli.KindName, "bond name").SetRequired().SetCompleter(completeLink(client, "bond")),
+			np("slave", libcli.KindName, "slave name").SetRequired().SetCompleter(completeLink(client, "")),
+		}, func(params map[string]string) error {
+			_, err := query(client.SetBondMaster, &networker.BondQuery{
+				Name:      params["name"],
+				SlaveName: params["slave"],
+			})
+			return err
+		}))
+
+	// release slave from bond by bond name and slave name
+	cli.AddCommandElem(
+		nce("bond", ""),
+		ncep("release", "release slave from bond", []*libcli.Param{
+			np("name", libcli.KindName, "bond name").SetRequired().SetCompleter(completeLink(client, "bond")),
+			np("slave", libcli.KindName, "slave name").SetRequired().SetCompleter(completeLink(client, "")),
+		}, func(params map[string]string) error {
+			_, err := query(client.UnsetBondMaster, &networker.BondQuery{
+				Name:      params["name"],
+				SlaveName: params["slave"],
+			})
+			return err
+		}))
+}
+
+func initCliVxlan(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show vxlans filtered by name and vni
+	cli.AddCommandElem(
+		nce("vxlan", ""),
+		ncep("show", "show vxlans", []*libcli.Param{
+			np("name", libcli.KindName, "vxlan name").SetCompleter(completeLink(client, "vxlan")),
+			np("vni", libcli.KindInt(1, maxVni), "vxlan network identifier"),
+		}, func(params map[string]string) error {
+			vni, _ := strconv.Atoi(params["vni"])
+			resp, err := query(client.ShowVxlan, &networker.VxlanQuery{
+				Name: params["name"],
+				Vni:  int32(vni),
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Addrs)
+			cli.PrintStructAll(resp.Vxlans)
+			return nil
 		}))
-}
 
-type nameRegex struct {
-	Name  string
-	Desc  string
-	Regex string
-}
+	// add vxlan by name, vni and options in any order
+	cli.AddCommandElem(
+		nce("vxlan", ""),
+		ncep("add", "add vxlan", []*libcli.Param{
+			np("name", libcli.KindName, "vxlan name").SetRequired(),
+			np("vni", libcli.KindInt(0, maxVni), "vxlan network identifier").SetRequired(),
+			np("local", libcli.KindIp, "local ip"),
+			np("remote", libcli.KindIp, "unicast remote vtep ip"),
+			np("group", libcli.KindIp, "multicast group ip"),
+			np("dstport", libcli.KindInt(1, 65535), "udp destination port").SetDefault(strconv.Itoa(vxlanPort)),
+			np("learning", kindOnOff, "learning of remote mac").SetDefault("on"),
+			np("dev", libcli.KindName, "underlay device").SetCompleter(completeLink(client, "")),
+		}, func(params map[string]string) error {
+			vni, _ := strconv.Atoi(params["vni"])
+			dstPort, _ := strconv.Atoi(params["dstport"])
+			resp, err := query(client.AddVxlan, &networker.VxlanQuery{
+				Name:     params["name"],
+				Vni:      int32(vni),
+				Local:    params["local"],
+				Remote:   params["remote"],
+				Group:    params["group"],
+				DstPort:  int32(dstPort),
+				Learning: params["learning"],
+				Device:   params["dev"],
+			})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.Vxlans)
+			return nil
+		}))
+
+	// del vxlan by vxlan name
+	cli.AddCommandElem(
+		nce("vxlan", ""),
+		ncep("del", "delete vxlan", []*libcli.Param{
+			np("name", libcli.KindName, "vxlan name").SetRequired().SetCompleter(completeLink(client, "vxlan")),
+		}, func(params map[string]string) error {
+			_, err := query(client.DelVxlan, &networker.VxlanQuery{
+				Name: params["name"],
+			})
+			return err
+		}))
 
-// get combinations of name and regex
-func getCombinations(args []nameRegex) [][]nameRegex {
-	var combinations [][]nameRegex
-	for i := 0; i < len(args); i++ {
-		var newCombinations [][]nameRegex
-		if len(combinations) == 0 {
-			newCombinations = append(newCombinations, []nameRegex{args[i]})
-		} else {
-			for _, combination := range combinations {
-				newCombinations = append(newCombinations, append(combination, args[i]))
+	// show fdb entries. entries of device of name if name is set
+	cli.AddCommandElem(
+		nce("fdb", ""),
+		ncep("show", "show fdb entries", []*libcli.Param{
+			np("name", libcli.KindName, "device name").SetCompleter(completeLink(client, "")),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowFdb, &networker.FdbQuery{
+				Name: params["name"],
+			})
+			if err != nil {
+				return err
 			}
-		}
-		combinations = append(combinations, newCombinations...)
-	}
-	return combinations
+			cli.PrintStructAll(resp.Fdbs)
+			return nil
+		}))
+
+	// add static fdb entry. eg. fdb add name vx0 dst 10.0.0.2 floods to remote vtep
+	cli.AddCommandElem(
+		nce("fdb", ""),
+		ncep("add", "add static fdb entry", []*libcli.Param{
+			np("name", libcli.KindName, "device name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("mac", libcli.KindMac, "mac address").SetDefault(zeroMac),
+			np("dst", libcli.KindIp, "remote vtep ip"),
+		}, func(params map[string]string) error {
+			_, err := query(client.AddFdb, &networker.FdbQuery{
+				Name: params["name"],
+				Mac:  params["mac"],
+				Dst:  params["dst"],
+			})
+			return err
+		}))
+
+	// del static fdb entry
+	cli.AddCommandElem(
+		nce("fdb", ""),
+		ncep("del", "delete static fdb entry", []*libcli.Param{
+			np("name", libcli.KindName, "device name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("mac", libcli.KindMac, "mac address").SetDefault(zeroMac),
+			np("dst", libcli.KindIp, "remote vtep ip"),
+		}, func(params map[string]string) error {
+			_, err := query(client.DelFdb, &networker.FdbQuery{
+				Name: params["name"],
+				Mac:  params["mac"],
+				Dst:  params["dst"],
+			})
+			return err
+		}))
 }
 
-func ruleAddCombination(cli *libcli.GoCli, combinationArgs []nameRegex) {
-	//get combination of args
+func initCliMacvlan(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show all macvlans
+	cli.AddCommandElem(
+		nce("macvlan", ""),
+		ncef("show", "show all macvlans", func(args []string) error {
+			resp, err := query(client.ShowMacvlan, &networker.MacvlanQuery{})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
+		}))
 
-	allCombination := make([][]nameRegex, 0)
+	// add macvlan by name, parent and mode in any order
+	cli.AddCommandElem(
+		nce("macvlan", ""),
+		ncep("add", "add macvlan", []*libcli.Param{
+			np("name", libcli.KindName, "macvlan name").SetRequired(),
+			np("parent", libcli.KindName, "parent name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("mode", kindMacvlanMode, "macvlan mode").SetDefault("bridge"),
+		}, func(params map[string]string) error {
+			resp, err := query(client.AddMacvlan, &networker.MacvlanQuery{
+				Name:       params["name"],
+				ParentName: params["parent"],
+				Mode:       params["mode"],
+			})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
+		}))
 
-	for i := 0; i < len(combinationArgs); i++ {
-		combination := getCombinations(combinationArgs[i:])
+	// del macvlan by macvlan name
+	cli.AddCommandElem(
+		nce("macvlan", ""),
+		ncep("del", "delete macvlan", []*libcli.Param{
+			np("name", libcli.KindName, "macvlan name").SetRequired().SetCompleter(completeLink(client, "macvlan")),
+		}, func(params map[string]string) error {
+			_, err := query(client.DelMacvlan, &networker.MacvlanQuery{
+				Name: params["name"],
+			})
+			return err
+		}))
 
-		allCombination = append(allCombination, combination...)
-	}
-	queryFunc := func(args []string) {
-		src := "any"
-		dst := "any"
-		sPort := "any"
-		dPort := "any"
-		proto := "any"
-		priority := 0
-
-		for i := 4; i < len(args); i += 2 {
-			switch args[i] {
-			case "src":
-				src = args[i+1]
-			case "dst":
-				dst = args[i+1]
-			case "sPort":
-				sPort = args[i+1]
-			case "dPort":
-				dPort = args[i+1]
-			case "proto":
-				proto = args[i+1]
-			case "priority":
-				priority, _ = strconv.Atoi(args[i+1])
+	// show all ipvlans
+	cli.AddCommandElem(
+		nce("ipvlan", ""),
+		ncef("show", "show all ipvlans", func(args []string) error {
+			resp, err := query(client.ShowIpvlan, &networker.IpvlanQuery{})
+			if err != nil {
+				return err
 			}
-		}
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
+		}))
 
-		resp, err := query(client.AddRule, &networker.RuleQuery{
-			Table:    args[3],
-			Priority: int32(priority),
-			Src:      src,
-			Dst:      dst,
-			SPort:    sPort,
-			DPort:    dPort,
-			IpProto:  proto,
-		})
+	// add ipvlan by name, parent and mode in any order
+	cli.AddCommandElem(
+		nce("ipvlan", ""),
+		ncep("add", "add ipvlan", []*libcli.Param{
+			np("name", libcli.KindName, "ipvlan name").SetRequired(),
+			np("parent", libcli.KindName, "parent name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("mode", kindIpvlanMode, "ipvlan mode").SetDefault("l3"),
+		}, func(params map[string]string) error {
+			resp, err := query(client.AddIpvlan, &networker.IpvlanQuery{
+				Name:       params["name"],
+				ParentName: params["parent"],
+				Mode:       params["mode"],
+			})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.NetLinks)
+			return nil
+		}))
+
+	// del ipvlan by ipvlan name
+	cli.AddCommandElem(
+		nce("ipvlan", ""),
+		ncep("del", "delete ipvlan", []*libcli.Param{
+			np("name", libcli.KindName, "ipvlan name").SetRequired().SetCompleter(completeLink(client, "ipvlan")),
+		}, func(params map[string]string) error {
+			_, err := query(client.DelIpvlan, &networker.IpvlanQuery{
+				Name: params["name"],
+			})
+			return err
+		}))
+}
+
+// complete names of tuntaps
+func completeTuntap(client networker.NetworkerClient) func(args []string) []string {
+	return func(args []string) []string {
+		resp, err := query(client.ShowTuntap, &networker.TuntapQuery{})
 		if err != nil {
-			fmt.Printf("%v\n", err)
-			return
+			return nil
 		}
-		libutil.PrintStructAll(resp.Rules)
-	}
 
-	for _, combination := range allCombination {
-		var args []string
-		var ceArgs []*libcli.CommandElem
-
-		ceArgs = append(ceArgs,
-			nce("rule", ""),
-			nce("add", "add rule by 5 tuple"),
-			nce("table", ""),
-			nce(libutil.TableRegex, "table name or number"))
-
-		for _, eachNameRegex := range combination {
-			args = append(args, eachNameRegex.Name, libutil.GetRegexHelpString(eachNameRegex.Regex))
-			ceArgs = append(ceArgs,
-				nce(eachNameRegex.Name, eachNameRegex.Desc),
-				nce(eachNameRegex.Regex, ""))
+		names := make([]string, 0)
+		for _, tuntap := range resp.Tuntaps {
+			names = append(names, tuntap.Name)
 		}
-		ceArgs[len(ceArgs)-1].Func = queryFunc
-
-		logger.Info("add rule %v", args)
-
-		cli.AddCommandElem(
-			ceArgs...)
-
+		return names
 	}
-	logger.Info("len %d", len(allCombination))
 }
 
-func initCliRule(cli *libcli.GoCli) {
-	// show all rules
+func initCliTuntap(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show all tuntaps
 	cli.AddCommandElem(
-		nce("rule", ""),
-		ncef("show", "show all rules", func(args []string) {
-			resp, err := query(client.ShowRule, &networker.RuleQuery{})
+		nce("tuntap", ""),
+		ncef("show", "show all tuntaps", func(args []string) error {
+			resp, err := query(client.ShowTuntap, &networker.TuntapQuery{})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Rules)
+			cli.PrintStructAll(resp.Tuntaps)
+			return nil
 		}))
 
-	// show rule by table
+	// add tuntap by name, mode and options in any order
 	cli.AddCommandElem(
-		nce("rule", ""),
-		nce("show", ""),
-		nce("table", ""),
-		ncef(libutil.TableRegex, "table name or num", func(args []string) {
-			resp, err := query(client.ShowRule, &networker.RuleQuery{
-				Table: args[3],
-			})
-			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
-			}
-			libutil.PrintStructAll(resp.Rules)
-		}))
-
-	ruleAddCombination(cli, []nameRegex{
-		{
-			Name:  "src",
-			Desc:  "source ip",
-			Regex: libutil.CidrRegex,
-		},
-		{
-			Name:  "dst",
-			Desc:  "destination cidr",
-			Regex: libutil.CidrRegex,
-		},
-		{
-			Name:  "sPort",
-			Desc:  "source port",
-			Regex: libutil.PortRegex,
-		},
-		{
-			Name:  "dPort",
-			Desc:  "destination port",
-			Regex: libutil.PortRegex,
-		},
-		{
-			Name:  "proto",
-			Desc:  "ip protocol",
-			Regex: libutil.ProtoRegex,
-		},
-	})
+		nce("tuntap", ""),
+		ncep("add", "add tuntap", []*libcli.Param{
+			np("name", libcli.KindName, "tuntap name").SetRequired(),
+			np("mode", kindTuntapMode, "tun or tap").SetRequired(),
+			np("owner", libcli.KindName, "user name or uid of owner"),
+			np("multiqueue", kindOnOff, "multi queue").SetDefault("off"),
+		}, func(params map[string]string) error {
+			resp, err := query(client.AddTuntap, &networker.TuntapQuery{
+				Name:       params["name"],
+				Mode:       params["mode"],
+				Owner:      params["owner"],
+				MultiQueue: params["multiqueue"],
+			})
+			if err != nil {
+				return err
+			}
+			cli.PrintStructAll(resp.Tuntaps)
+			return nil
+		}))
 
-	// del rule by table
+	// del tuntap by tuntap name
 	cli.AddCommandElem(
-		nce("rule", ""),
-		nce("del", "delete rule by table id or table id and priority"),
-		nce("table", ""),
-		ncef(libutil.TableRegex, "table name or number", func(args []string) {
-			resp, err := query(client.DelRule, &networker.RuleQuery{
-				Table:    args[3],
-				Priority: 0,
-				Src:      "any",
-				Dst:      "any",
-				SPort:    "any",
-				DPort:    "any",
-				IpProto:  "any",
+		nce("tuntap", ""),
+		ncep("del", "delete tuntap", []*libcli.Param{
+			np("name", libcli.KindName, "tuntap name").SetRequired().SetCompleter(completeTuntap(client)),
+		}, func(params map[string]string) error {
+			_, err := query(client.DelTuntap, &networker.TuntapQuery{
+				Name: params["name"],
 			})
+			return err
+		}))
+}
+
+func initCliNetns(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show all netns
+	cli.AddCommandElem(
+		nce("netns", ""),
+		ncef("show", "show all netns", func(args []string) error {
+			resp, err := query(client.ShowNetns, &networker.NetnsQuery{})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Rules)
+			cli.PrintStructAll(resp.Netns)
+			return nil
 		}))
 
-	// del rule by table and priority
+	// add netns by netns name
 	cli.AddCommandElem(
-		nce("rule", ""),
-		nce("del", ""),
-		nce("table", ""),
-		nce(libutil.TableRegex, "table name or number"),
-		nce("priority", "priority which whil be deleted"),
-		ncef(libutil.NumberRegex, "priority", func(args []string) {
-			priority, _ := strconv.Atoi(args[5])
-			resp, err := query(client.DelRule, &networker.RuleQuery{
-				Table:    args[3],
-				Priority: int32(priority),
-				Src:      "any",
-				Dst:      "any",
-				SPort:    "any",
-				DPort:    "any",
-				IpProto:  "any",
+		nce("netns", ""),
+		ncep("add", "add netns", []*libcli.Param{
+			np("name", libcli.KindName, "netns name").SetRequired(),
+		}, func(params map[string]string) error {
+			resp, err := query(client.AddNetns, &networker.NetnsQuery{
+				Name: params["name"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Rules)
+			cli.PrintStructAll(resp.Netns)
+			return nil
+		}))
+
+	// del netns by netns name
+	cli.AddCommandElem(
+		nce("netns", ""),
+		ncep("del", "delete netns", []*libcli.Param{
+			np("name", libcli.KindName, "netns name").SetRequired().SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			_, err := query(client.DelNetns, &networker.NetnsQuery{
+				Name: params["name"],
+			})
+			return err
+		}))
+
+	// move link into netns by netns name and link name
+	cli.AddCommandElem(
+		nce("netns", ""),
+		ncep("set", "move link into netns", []*libcli.Param{
+			np("name", libcli.KindName, "netns name").SetRequired().SetCompleter(completeNetns(client)),
+			np("link", libcli.KindName, "link name").SetRequired().SetCompleter(completeLink(client, "")),
+		}, func(params map[string]string) error {
+			_, err := query(client.SetNetnsLink, &networker.NetnsQuery{
+				Name:     params["name"],
+				LinkName: params["link"],
+			})
+			return err
+		}))
+
+	// move link out of netns by netns name and link name
+	cli.AddCommandElem(
+		nce("netns", ""),
+		ncep("unset", "move link out of netns", []*libcli.Param{
+			np("name", libcli.KindName, "netns name").SetRequired().SetCompleter(completeNetns(client)),
+			np("link", libcli.KindName, "link name").SetRequired().SetCompleter(completeNetnsLink(client)),
+		}, func(params map[string]string) error {
+			_, err := query(client.UnsetNetnsLink, &networker.NetnsQuery{
+				Name:     params["name"],
+				LinkName: params["link"],
+			})
+			return err
 		}))
 }
 
-func initCliRoute(cli *libcli.GoCli) {
-	// show route
+func initCliAddr(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show addresses filtered by name, family and netns
 	cli.AddCommandElem(
-		nce("route", ""),
-		ncef("show", "show all routes", func(args []string) {
-			resp, err := query(client.ShowRoute, &networker.RouteQuery{})
+		nce("addr", ""),
+		ncep("show", "show addresses", []*libcli.Param{
+			np("name", libcli.KindName, "address name").SetCompleter(completeLink(client, "")),
+			np("family", libcli.KindFamily, "inet or inet6"),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowAddr, &networker.AddrQuery{
+				Name:   params["name"],
+				Family: params["family"],
+				Netns:  params["netns"],
+			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Addrs)
+			return nil
 		}))
 
-	// show route by table
+	// add ip with mask by name
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("show", ""),
-		nce("table", ""),
-		ncef(libutil.TableRegex, "table name or num", func(args []string) {
-			resp, err := query(client.ShowRoute, &networker.RouteQuery{
-				Table: args[3],
+		nce("addr", ""),
+		ncep("add", "add ip with mask", []*libcli.Param{
+			np("name", libcli.KindName, "address name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("ip-with-mask", libcli.KindCidr, "ip with mask").SetRequired(),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.AddAddr, &networker.AddrQuery{
+				Name:       params["name"],
+				IpWithMask: params["ip-with-mask"],
+				Netns:      params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Addrs)
+			return nil
 		}))
 
-	// add route by destination and nexthop
+	// del ip with mask by name
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("add", "add route"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
-			resp, err := query(client.AddRoute, &networker.RouteQuery{
-				Table:       "main",
-				Protocol:    "static",
-				Destination: args[3],
-				NextHop:     args[5],
+		nce("addr", ""),
+		ncep("del", "delete ip with mask", []*libcli.Param{
+			np("name", libcli.KindName, "address name").SetRequired().SetCompleter(completeLink(client, "")),
+			np("ip-with-mask", libcli.KindCidr, "ip with mask").SetRequired(),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.DelAddr, &networker.AddrQuery{
+				Name:       params["name"],
+				IpWithMask: params["ip-with-mask"],
+				Netns:      params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Addrs)
+			return nil
 		}))
+}
 
-	// add route by destination and nexthop and table
+func initCliRule(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show rules filtered by table, family and netns
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("add", "add route"),
-		nce("table", ""),
-		nce(libutil.TableRegex, "table number"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
-			resp, err := query(client.AddRoute, &networker.RouteQuery{
-				Table:       args[3],
-				Protocol:    "static",
-				Destination: args[5],
-				NextHop:     args[7],
+		nce("rule", ""),
+		ncep("show", "show rules", []*libcli.Param{
+			np("table", libcli.KindTable, "table name or num").SetCompleter(completeRuleTable(client)),
+			np("family", libcli.KindFamily, "inet or inet6"),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowRule, &networker.RuleQuery{
+				Table:  params["table"],
+				Family: params["family"],
+				Netns:  params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Rules)
+			return nil
 		}))
 
-	// add route by destination, source and nexthop
+	// add rule by table and 5 tuple in any order
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("add", "add route"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("src", "source ip"),
-		nce(libutil.CidrRegex, "source ip"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
-			resp, err := query(client.AddRoute, &networker.RouteQuery{
-				Table:       "main",
-				Protocol:    "static",
-				Destination: args[3],
-				Source:      args[5],
-				NextHop:     args[7],
+		nce("rule", ""),
+		ncep("add", "add rule by 5 tuple", []*libcli.Param{
+			np("table", libcli.KindTable, "table name or number").SetRequired().SetCompleter(completeRuleTable(client)),
+			np("src", libcli.KindCidr, "source cidr").SetDefault("any"),
+			np("dst", libcli.KindCidr, "destination cidr").SetDefault("any"),
+			np("s-port", libcli.KindPortRange, "source port").SetDefault("any"),
+			np("d-port", libcli.KindPortRange, "destination port").SetDefault("any"),
+			np("proto", libcli.KindProto, "ip protocol").SetDefault("any"),
+			np("priority", libcli.KindInt(0, math.MaxInt32), "priority").SetDefault("0"),
+			np("family", libcli.KindFamily, "family if src and dst are any").SetDefault("inet"),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			priority, _ := strconv.Atoi(params["priority"])
+			resp, err := query(client.AddRule, &networker.RuleQuery{
+				Table:    params["table"],
+				Priority: int32(priority),
+				Src:      params["src"],
+				Dst:      params["dst"],
+				SPort:    params["s-port"],
+				DPort:    params["d-port"],
+				IpProto:  params["proto"],
+				Family:   params["family"],
+				Netns:    params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Rules)
+			return nil
 		}))
 
-	// add route by destination, source, nexthop and table
+	// del rule by table and priority. priority 0 deletes rules of table of any priority
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("add", "add route"),
-		nce("table", ""),
-		nce(libutil.TableRegex, "table number"),
-		nce("src", "source ip"),
-		nce(libutil.CidrRegex, "source ip"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("source", "source ip"),
-		nce(libutil.IpRegex, "source ip"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
-			resp, err := query(client.AddRoute, &networker.RouteQuery{
-				Table:       args[3],
-				Protocol:    "static",
-				Destination: args[5],
-				Source:      args[7],
-				NextHop:     args[9],
+		nce("rule", ""),
+		ncep("del", "delete rule by table or table and priority", []*libcli.Param{
+			np("table", libcli.KindTable, "table name or number").SetRequired().SetCompleter(completeRuleTable(client)),
+			np("priority", libcli.KindInt(0, math.MaxInt32), "priority").SetDefault("0"),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			priority, _ := strconv.Atoi(params["priority"])
+			resp, err := query(client.DelRule, &networker.RuleQuery{
+				Table:    params["table"],
+				Priority: int32(priority),
+				Src:      "any",
+				Dst:      "any",
+				SPort:    "any",
+				DPort:    "any",
+				IpProto:  "any",
+				Netns:    params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Rules)
+			return nil
 		}))
+}
 
-	// del route by destination
+func initCliRoute(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show routes filtered by table, family and netns
 	cli.AddCommandElem(
 		nce("route", ""),
-		nce("del", "delete route"),
-		nce("dst", "destination cidr"),
-		ncef(libutil.CidrRegex, "destination cidr", func(args []string) {
-			resp, err := query(client.DelRoute, &networker.RouteQuery{
-				Table:       "main",
-				Protocol:    "static",
-				Destination: args[3],
-				NextHop:     "any",
+		ncep("show", "show routes", []*libcli.Param{
+			np("table", libcli.KindTable, "table name or num").SetCompleter(completeRouteTable(client)),
+			np("family", libcli.KindFamily, "inet or inet6"),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.ShowRoute, &networker.RouteQuery{
+				Table:  params["table"],
+				Family: params["family"],
+				Netns:  params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Routes)
+			return nil
 		}))
 
-	// del route by destination and table
+	// add route by destination and nexthop with table, source and device in any order.
+	// device is of link local nexthop of ipv6
 	cli.AddCommandElem(
 		nce("route", ""),
-		nce("del", "delete route"),
-		nce("table", ""),
-		nce(libutil.TableRegex, "table number"),
-		nce("dst", "destination cidr"),
-		ncef(libutil.CidrRegex, "destination cidr", func(args []string) {
-			resp, err := query(client.DelRoute, &networker.RouteQuery{
-				Table:       args[3],
-				Protocol:    "static",
-				Destination: args[5],
-				NextHop:     "any",
+		ncep("add", "add route", []*libcli.Param{
+			np("dst", libcli.KindCidr, "destination cidr").SetRequired(),
+			np("nexthop", libcli.KindNextHop, "nexthop ip or multipath ip@dev,ip@dev").SetRequired(),
+			np("table", libcli.KindTable, "table name or number").SetDefault("main").SetCompleter(completeRouteTable(client)),
+			np("src", libcli.KindIp, "source ip"),
+			np("dev", libcli.KindName, "device of nexthop").SetCompleter(completeLink(client, "")),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
+			resp, err := query(client.AddRoute, &networker.RouteQuery{
+				Table:       params["table"],
+				Destination: params["dst"],
+				Source:      params["src"],
+				NextHop:     params["nexthop"],
+				Device:      params["dev"],
+				Netns:       params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(resp.Routes)
+			return nil
 		}))
 
-	// del route by destination and nexthop
+	// del route by destination with table, source and nexthop in any order
 	cli.AddCommandElem(
 		nce("route", ""),
-		nce("del", "delete route"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
+		ncep("del", "delete route", []*libcli.Param{
+			np("dst", libcli.KindCidr, "destination cidr").SetRequired(),
+			np("nexthop", libcli.KindNextHop, "nexthop ip or multipath ip@dev,ip@dev").SetDefault("any"),
+			np("table", libcli.KindTable, "table name or number").SetDefault("main").SetCompleter(completeRouteTable(client)),
+			np("src", libcli.KindIp, "source ip"),
+			np("netns", libcli.KindName, "netns name").SetCompleter(completeNetns(client)),
+		}, func(params map[string]string) error {
 			resp, err := query(client.DelRoute, &networker.RouteQuery{
-				Table:       "main",
-				Protocol:    "static",
-				Destination: args[3],
-				NextHop:     args[5],
+				Table:       params["table"],
+				Destination: params["dst"],
+				Source:      params["src"],
+				NextHop:     params["nexthop"],
+				Netns:       params["netns"],
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
+			}
+			cli.PrintStructAll(resp.Routes)
+			return nil
+		}))
+}
+
+// row of show diff
+type diff struct {
+	Op     string
+	Type   string
+	Config string
+}
+
+// convert candidate changes to diff rows
+func changesToDiffs(changes []*networker.Change) []diff {
+	diffs := make([]diff, 0)
+	for _, change := range changes {
+		op := "+"
+		if change.Op == opDel {
+			op = "-"
+		}
+
+		changeType, config := getChangeConfig(change)
+		diffs = append(diffs, diff{Op: op, Type: changeType, Config: config})
+	}
+
+	return diffs
+}
+
+func initCliCandidate(cli *libcli.GoCli, client networker.NetworkerClient) {
+	// show staged changes of candidate
+	cli.AddCommandElem(
+		nce("show", ""),
+		ncef("diff", "show uncommitted changes", func(args []string) error {
+			resp, err := query(client.ShowCandidate, &networker.CandidateQuery{})
+			if err != nil {
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.PrintStructAll(changesToDiffs(resp.Changes))
+			return nil
 		}))
 
-	// del route by destination, nexthop and table
+	// commit candidate
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("del", "delete route"),
-		nce("table", ""),
-		nce(libutil.TableRegex, "table number"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
-			resp, err := query(client.DelRoute, &networker.RouteQuery{
-				Table:       args[3],
-				Protocol:    "static",
-				Destination: args[5],
-				NextHop:     args[7],
-			})
+		ncef("commit", "apply uncommitted changes", func(args []string) error {
+			resp, err := query(client.Commit, &networker.CommitQuery{})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.Printf("%d change(s) committed\n", len(resp.Changes))
+			return nil
 		}))
 
-	// del route by destination, source and nexthop
+	// commit candidate and rollback unless confirmed in duration
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("del", "delete route"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("src", "source ip"),
-		nce(libutil.CidrRegex, "source ip"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
-			resp, err := query(client.DelRoute, &networker.RouteQuery{
-				Table:       "main",
-				Protocol:    "static",
-				Destination: args[3],
-				Source:      args[5],
-				NextHop:     args[7],
+		nce("commit", "apply uncommitted changes"),
+		nce("confirmed", "rollback unless confirmed in duration"),
+		ncaf(libcli.KindDuration, "time to confirm", func(args []string) error {
+			duration, err := time.ParseDuration(args[2])
+			if err != nil {
+				return err
+			}
+
+			resp, err := query(client.Commit, &networker.CommitQuery{
+				ConfirmSeconds: int64(math.Ceil(duration.Seconds())),
 			})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.Printf("%d change(s) committed, rollback in %v unless confirmed by commit confirm\n",
+				len(resp.Changes), duration)
+			return nil
 		}))
 
-	// del route by destination, source, nexthop and table
+	// confirm pending commit
 	cli.AddCommandElem(
-		nce("route", ""),
-		nce("del", "delete route"),
-		nce("table", ""),
-		nce(libutil.TableRegex, "table number"),
-		nce("dst", "destination cidr"),
-		nce(libutil.CidrRegex, "destination cidr"),
-		nce("src", "source ip"),
-		nce(libutil.CidrRegex, "source ip"),
-		nce("nexthop", "nexthop ip"),
-		ncef(libutil.IpRegex, "nexthop ip", func(args []string) {
-			resp, err := query(client.DelRoute, &networker.RouteQuery{
-				Table:       args[3],
-				Protocol:    "static",
-				Destination: args[5],
-				Source:      args[7],
-				NextHop:     args[9],
-			})
+		nce("commit", "apply uncommitted changes"),
+		ncef("confirm", "confirm commit confirmed", func(args []string) error {
+			_, err := query(client.ConfirmCommit, &networker.CommitQuery{})
+			return err
+		}))
+
+	// discard candidate
+	cli.AddCommandElem(
+		ncef("discard", "discard uncommitted changes", func(args []string) error {
+			resp, err := query(client.DiscardCandidate, &networker.CandidateQuery{})
 			if err != nil {
-				fmt.Printf("%v\n", err)
-				return
+				return err
 			}
-			libutil.PrintStructAll(resp.Routes)
+			cli.Printf("%d change(s) discarded\n", len(resp.Changes))
+			return nil
 		}))
 }
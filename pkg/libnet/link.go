@@ -9,7 +9,22 @@ import (
 	"github.com/vishvananda/netlink"
 )
 
+// get name of link option. empty if option is not set. eg. 802.3ad of bond mode
+func optionToString[T comparable](option T, stringToOption map[string]T) string {
+	for name, value := range stringToOption {
+		if value == option {
+			return name
+		}
+	}
+	return ""
+}
+
 func getLinkTypeString(link netlink.Link) string {
+	// type of tuntap is tun or tap
+	if tuntap, ok := link.(*netlink.Tuntap); ok {
+		return tuntap.Mode.String()
+	}
+
 	t := reflect.TypeOf(link).Elem()
 
 	switch t {
@@ -21,13 +36,29 @@ func getLinkTypeString(link netlink.Link) string {
 		return "vlan"
 	case reflect.TypeOf(netlink.Veth{}):
 		return "veth"
+	case reflect.TypeOf(netlink.Bond{}):
+		return "bond"
+	case reflect.TypeOf(netlink.Vxlan{}):
+		return "vxlan"
+	case reflect.TypeOf(netlink.Macvlan{}):
+		return "macvlan"
+	case reflect.TypeOf(netlink.IPVlan{}):
+		return "ipvlan"
 	default:
 		return "unknown"
 	}
 }
 
-func (s *server) listLink() ([]*networker.NetLink, error) {
-	linkSlice, err := netlink.LinkList()
+// list links of netns. netns of daemon if netns name is empty
+func (s *server) listLink(netnsName string) ([]*networker.NetLink, error) {
+	handle, err := getHandle(netnsName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	linkSlice, err := handle.LinkList()
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
@@ -38,12 +69,15 @@ func (s *server) listLink() ([]*networker.NetLink, error) {
 		var parentName string
 		var masterName string
 
-		parentLink, err := netlink.LinkByIndex(link.Attrs().ParentIndex)
-		if err == nil {
-			parentName = parentLink.Attrs().Name
+		// parent in other netns is not resolved. eg. peer of veth moved into netns
+		if link.Attrs().NetNsID < 0 {
+			parentLink, err := handle.LinkByIndex(link.Attrs().ParentIndex)
+			if err == nil {
+				parentName = parentLink.Attrs().Name
+			}
 		}
 
-		masterLink, err := netlink.LinkByIndex(link.Attrs().MasterIndex)
+		masterLink, err := handle.LinkByIndex(link.Attrs().MasterIndex)
 		if err == nil {
 			masterName = masterLink.Attrs().Name
 		}
@@ -58,6 +92,24 @@ func (s *server) listLink() ([]*networker.NetLink, error) {
 			vlanProtocol = netlink.VlanProtocolToString[vlan.VlanProtocol]
 		}
 
+		// parent of vxlan is underlay device
+		vni := int32(0)
+		if typeName == "vxlan" {
+			vxlan, _ := link.(*netlink.Vxlan)
+			vni = int32(vxlan.VxlanId)
+			if underlay, err := handle.LinkByIndex(vxlan.VtepDevIndex); err == nil {
+				parentName = underlay.Attrs().Name
+			}
+		}
+
+		mode := ""
+		switch link := link.(type) {
+		case *netlink.Macvlan:
+			mode = optionToString(link.Mode, stringToMacvlanMode)
+		case *netlink.IPVlan:
+			mode = optionToString(link.Mode, stringToIpvlanMode)
+		}
+
 		logger.Info("%s %s %v", typeName, reflect.TypeOf(link), link)
 		linkList = append(linkList, &networker.NetLink{
 			Name:         link.Attrs().Name,
@@ -68,6 +120,8 @@ func (s *server) listLink() ([]*networker.NetLink, error) {
 			Master:       masterName,
 			VlanId:       vlanId,
 			VlanProtocol: vlanProtocol,
+			Vni:          vni,
+			Mode:         mode,
 		})
 	}
 
@@ -75,18 +129,31 @@ func (s *server) listLink() ([]*networker.NetLink, error) {
 }
 
 func (s *server) ShowNetLink(ctx context.Context, in *networker.NetLinkQuery) (*networker.NetLinkResponse, error) {
-	linkList, err := s.listLink()
+	linkList, err := s.listLink(in.Netns)
 	return &networker.NetLinkResponse{NetLinks: linkList}, err
 }
 
 func (s *server) SetNetLinkMac(ctx context.Context, in *networker.NetLinkQuery) (*networker.NetLinkResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	err = netlink.LinkSetHardwareAddr(link, net.HardwareAddr(in.Mac))
+	mac, err := net.ParseMAC(in.Mac)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = handle.LinkSetHardwareAddr(link, mac)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
@@ -96,28 +163,42 @@ func (s *server) SetNetLinkMac(ctx context.Context, in *networker.NetLinkQuery)
 }
 
 func (s *server) SetNetLinkUp(ctx context.Context, in *networker.NetLinkQuery) (*networker.NetLinkResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkSetUp(link)
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	handle.LinkSetUp(link)
 	return &networker.NetLinkResponse{}, err
 }
 
 func (s *server) SetNetLinkDown(ctx context.Context, in *networker.NetLinkQuery) (*networker.NetLinkResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkSetDown(link)
+	handle.LinkSetDown(link)
 	return &networker.NetLinkResponse{}, err
 }
 
 // bridge
 func (s *server) ShowBridge(ctx context.Context, in *networker.BridgeQuery) (*networker.NetLinkResponse, error) {
-	linkList, err := s.listLink()
+	linkList, err := s.listLink(in.Netns)
 	bridgeList := make([]*networker.NetLink, 0)
 	for _, link := range linkList {
 		if link.Type == "bridge" {
@@ -131,7 +212,7 @@ func (s *server) ShowBridge(ctx context.Context, in *networker.BridgeQuery) (*ne
 func (s *server) ShowBridgeSlave(ctx context.Context, in *networker.BridgeQuery) (*networker.NetLinkResponse, error) {
 	master := in.Name
 
-	linkList, err := s.listLink()
+	linkList, err := s.listLink(in.Netns)
 	slaveList := make([]*networker.NetLink, 0)
 	for _, link := range linkList {
 		if link.Master == master {
@@ -143,21 +224,28 @@ func (s *server) ShowBridgeSlave(ctx context.Context, in *networker.BridgeQuery)
 }
 
 func (s *server) AddBridge(ctx context.Context, in *networker.BridgeQuery) (*networker.NetLinkResponse, error) {
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
 	linkAttrs := netlink.NewLinkAttrs()
 	linkAttrs.Name = in.Name
 	newBridge := &netlink.Bridge{LinkAttrs: linkAttrs}
-	err := netlink.LinkAdd(newBridge)
+	err = handle.LinkAdd(newBridge)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	link, err := netlink.LinkByName(in.Name)
+	link, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkSetUp(link)
+	handle.LinkSetUp(link)
 
 	bridgeList := make([]*networker.NetLink, 0)
 	bridgeList = append(bridgeList, &networker.NetLink{
@@ -171,27 +259,41 @@ func (s *server) AddBridge(ctx context.Context, in *networker.BridgeQuery) (*net
 }
 
 func (s *server) DelBridge(ctx context.Context, in *networker.BridgeQuery) (*networker.NetLinkResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkDel(link)
+	handle.LinkDel(link)
 	return &networker.NetLinkResponse{}, err
 }
 
 func (s *server) SetBridgeMaster(ctx context.Context, in *networker.BridgeQuery) (*networker.NetLinkResponse, error) {
-	bridge, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	bridge, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	slave, err := netlink.LinkByName(in.SlaveName)
+	slave, err := handle.LinkByName(in.SlaveName)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	err = netlink.LinkSetMaster(slave, bridge)
+	err = handle.LinkSetMaster(slave, bridge)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
@@ -201,13 +303,20 @@ func (s *server) SetBridgeMaster(ctx context.Context, in *networker.BridgeQuery)
 }
 
 func (s *server) UnsetBridgeMaster(ctx context.Context, in *networker.BridgeQuery) (*networker.NetLinkResponse, error) {
-	slave, err := netlink.LinkByName(in.SlaveName)
+	handle, err := getHandle(in.Netns)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
+	defer handle.Delete()
 
-	err = netlink.LinkSetNoMaster(slave)
+	slave, err := handle.LinkByName(in.SlaveName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = handle.LinkSetNoMaster(slave)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
@@ -218,7 +327,7 @@ func (s *server) UnsetBridgeMaster(ctx context.Context, in *networker.BridgeQuer
 
 // veth
 func (s *server) ShowVeth(ctx context.Context, in *networker.VethQuery) (*networker.NetLinkResponse, error) {
-	linkList, err := s.listLink()
+	linkList, err := s.listLink(in.Netns)
 	vethList := make([]*networker.NetLink, 0)
 	for _, link := range linkList {
 		if link.Type == "veth" {
@@ -230,30 +339,57 @@ func (s *server) ShowVeth(ctx context.Context, in *networker.VethQuery) (*networ
 }
 
 func (s *server) AddVeth(ctx context.Context, in *networker.VethQuery) (*networker.NetLinkResponse, error) {
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
 	linkAttrs := netlink.NewLinkAttrs()
 	linkAttrs.Name = in.Name
 	newVeth := &netlink.Veth{
 		LinkAttrs: linkAttrs,
 		PeerName:  in.PeerName,
 	}
-	err := netlink.LinkAdd(newVeth)
+
+	// peer is created in netns of peer, or in netns of veth if not set
+	peerHandle := handle
+	if in.PeerNetns != "" {
+		peerHandle, err = getHandle(in.PeerNetns)
+		if err != nil {
+			logger.Warn("%v\n", err)
+			return nil, err
+		}
+		defer peerHandle.Delete()
+
+		ns, err := getNetns(in.PeerNetns)
+		if err != nil {
+			logger.Warn("%v\n", err)
+			return nil, err
+		}
+		defer ns.Close()
+		newVeth.PeerNamespace = netlink.NsFd(ns)
+	}
+
+	err = handle.LinkAdd(newVeth)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	link1, err := netlink.LinkByName(in.Name)
+	link1, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	link2, err := netlink.LinkByName(in.PeerName)
+	link2, err := peerHandle.LinkByName(in.PeerName)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkSetUp(link1)
-	netlink.LinkSetUp(link2)
+	handle.LinkSetUp(link1)
+	peerHandle.LinkSetUp(link2)
 
 	vethList := make([]*networker.NetLink, 0)
 	vethList = append(vethList, &networker.NetLink{
@@ -266,18 +402,25 @@ func (s *server) AddVeth(ctx context.Context, in *networker.VethQuery) (*network
 }
 
 func (s *server) DelVeth(ctx context.Context, in *networker.VethQuery) (*networker.NetLinkResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkDel(link)
+	handle.LinkDel(link)
 	return &networker.NetLinkResponse{}, err
 }
 
 // vlan
 func (s *server) ShowVlan(ctx context.Context, in *networker.VlanQuery) (*networker.NetLinkResponse, error) {
-	linkList, err := s.listLink()
+	linkList, err := s.listLink(in.Netns)
 	vlanList := make([]*networker.NetLink, 0)
 	for _, link := range linkList {
 		if link.Type == "vlan" {
@@ -291,7 +434,14 @@ func (s *server) ShowVlan(ctx context.Context, in *networker.VlanQuery) (*networ
 }
 
 func (s *server) AddVlan(ctx context.Context, in *networker.VlanQuery) (*networker.NetLinkResponse, error) {
-	parent, err := netlink.LinkByName(in.ParentName)
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	parent, err := handle.LinkByName(in.ParentName)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
@@ -306,29 +456,36 @@ func (s *server) AddVlan(ctx context.Context, in *networker.VlanQuery) (*network
 		VlanProtocol: netlink.VLAN_PROTOCOL_8021Q,
 	}
 
-	err = netlink.LinkAdd(newVlan)
+	err = handle.LinkAdd(newVlan)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	link, err := netlink.LinkByName(in.Name)
+	link, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkSetUp(link)
+	handle.LinkSetUp(link)
 
 	return &networker.NetLinkResponse{}, err
 
 }
 
 func (s *server) DelVlan(ctx context.Context, in *networker.VlanQuery) (*networker.NetLinkResponse, error) {
-	link, err := netlink.LinkByName(in.Name)
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.Name)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
-	netlink.LinkDel(link)
+	handle.LinkDel(link)
 	return &networker.NetLinkResponse{}, err
 }
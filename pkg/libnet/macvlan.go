@@ -0,0 +1,165 @@
+package libnet
+
+import (
+	"context"
+	"go-cli/pkg/libnet/networker"
+
+	"github.com/vishvananda/netlink"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// modes of macvlan. source mode is not supported
+var stringToMacvlanMode = map[string]netlink.MacvlanMode{
+	"private":  netlink.MACVLAN_MODE_PRIVATE,
+	"vepa":     netlink.MACVLAN_MODE_VEPA,
+	"bridge":   netlink.MACVLAN_MODE_BRIDGE,
+	"passthru": netlink.MACVLAN_MODE_PASSTHRU,
+}
+
+// modes of ipvlan
+var stringToIpvlanMode = map[string]netlink.IPVlanMode{
+	"l2":  netlink.IPVLAN_MODE_L2,
+	"l3":  netlink.IPVLAN_MODE_L3,
+	"l3s": netlink.IPVLAN_MODE_L3S,
+}
+
+// get link attrs of sub interface on parent
+func getSubLinkAttrs(name string, parentName string) (netlink.LinkAttrs, error) {
+	linkAttrs := netlink.NewLinkAttrs()
+	linkAttrs.Name = name
+
+	parent, err := netlink.LinkByName(parentName)
+	if err != nil {
+		return linkAttrs, err
+	}
+	linkAttrs.ParentIndex = parent.Attrs().Index
+
+	return linkAttrs, nil
+}
+
+// add link and set it up
+func addLink(link netlink.Link) error {
+	err := netlink.LinkAdd(link)
+	if err != nil {
+		return err
+	}
+
+	newLink, err := netlink.LinkByName(link.Attrs().Name)
+	if err != nil {
+		return err
+	}
+	return netlink.LinkSetUp(newLink)
+}
+
+// del link of link type. eg. macvlan
+func delLink(name string, linkType string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return err
+	}
+
+	if getLinkTypeString(link) != linkType {
+		return status.Errorf(codes.InvalidArgument, "link \"%s\" is not a %s", name, linkType)
+	}
+	return netlink.LinkDel(link)
+}
+
+// show links of link type filtered by name
+func (s *server) showLinkOfType(linkType string, name string) (*networker.NetLinkResponse, error) {
+	linkList, err := s.listLink("")
+	filtered := make([]*networker.NetLink, 0)
+	for _, link := range linkList {
+		if link.Type == linkType && (name == "" || name == link.Name) {
+			filtered = append(filtered, link)
+		}
+	}
+
+	return &networker.NetLinkResponse{NetLinks: filtered}, err
+}
+
+// macvlan
+func (s *server) ShowMacvlan(ctx context.Context, in *networker.MacvlanQuery) (*networker.NetLinkResponse, error) {
+	return s.showLinkOfType("macvlan", in.Name)
+}
+
+// add macvlan on parent. bridge mode if mode is empty
+func (s *server) AddMacvlan(ctx context.Context, in *networker.MacvlanQuery) (*networker.NetLinkResponse, error) {
+	modeString := in.Mode
+	if modeString == "" {
+		modeString = "bridge"
+	}
+	mode, ok := stringToMacvlanMode[modeString]
+	if !ok {
+		err := status.Errorf(codes.InvalidArgument, "unknown macvlan mode \"%s\"", in.Mode)
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	linkAttrs, err := getSubLinkAttrs(in.Name, in.ParentName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = addLink(&netlink.Macvlan{LinkAttrs: linkAttrs, Mode: mode})
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return s.ShowMacvlan(ctx, &networker.MacvlanQuery{Name: in.Name})
+}
+
+func (s *server) DelMacvlan(ctx context.Context, in *networker.MacvlanQuery) (*networker.NetLinkResponse, error) {
+	err := delLink(in.Name, "macvlan")
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.NetLinkResponse{}, nil
+}
+
+// ipvlan
+func (s *server) ShowIpvlan(ctx context.Context, in *networker.IpvlanQuery) (*networker.NetLinkResponse, error) {
+	return s.showLinkOfType("ipvlan", in.Name)
+}
+
+// add ipvlan on parent. l3 mode if mode is empty, same as kernel
+func (s *server) AddIpvlan(ctx context.Context, in *networker.IpvlanQuery) (*networker.NetLinkResponse, error) {
+	modeString := in.Mode
+	if modeString == "" {
+		modeString = "l3"
+	}
+	mode, ok := stringToIpvlanMode[modeString]
+	if !ok {
+		err := status.Errorf(codes.InvalidArgument, "unknown ipvlan mode \"%s\"", in.Mode)
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	linkAttrs, err := getSubLinkAttrs(in.Name, in.ParentName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = addLink(&netlink.IPVlan{LinkAttrs: linkAttrs, Mode: mode})
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return s.ShowIpvlan(ctx, &networker.IpvlanQuery{Name: in.Name})
+}
+
+func (s *server) DelIpvlan(ctx context.Context, in *networker.IpvlanQuery) (*networker.NetLinkResponse, error) {
+	err := delLink(in.Name, "ipvlan")
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.NetLinkResponse{}, nil
+}
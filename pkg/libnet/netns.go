@@ -0,0 +1,254 @@
+package libnet
+
+import (
+	"context"
+	"errors"
+	"go-cli/pkg/libnet/networker"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// path of named netns. same as ip netns
+const netnsPath = "/run/netns"
+
+// check name of netns. name is a file of netns path
+func checkNetnsName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return status.Errorf(codes.InvalidArgument, "invalid netns name \"%s\"", name)
+	}
+	return nil
+}
+
+// get netns of name. netns must be closed after use
+func getNetns(name string) (netns.NsHandle, error) {
+	if err := checkNetnsName(name); err != nil {
+		return netns.None(), err
+	}
+
+	ns, err := netns.GetFromPath(path.Join(netnsPath, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return netns.None(), status.Errorf(codes.NotFound, "netns \"%s\" does not exist", name)
+	}
+	return ns, err
+}
+
+// check netns exists. netns of daemon if name is empty
+func checkNetns(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	ns, err := getNetns(name)
+	if err != nil {
+		return err
+	}
+	return ns.Close()
+}
+
+// get netlink handle of netns. handle of netns of daemon if name is empty.
+// handle must be deleted after use
+func getHandle(name string) (*netlink.Handle, error) {
+	if name == "" {
+		return &netlink.Handle{}, nil
+	}
+
+	ns, err := getNetns(name)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+
+	return netlink.NewHandleAt(ns)
+}
+
+// create named netns. new netns is set to current thread by netns,
+// so thread is locked until netns of thread is restored
+func newNamedNetns(name string) error {
+	runtime.LockOSThread()
+	origin, err := netns.Get()
+	if err != nil {
+		runtime.UnlockOSThread()
+		return err
+	}
+	defer origin.Close()
+
+	ns, err := netns.NewNamed(name)
+	if err == nil {
+		ns.Close()
+	}
+
+	// thread is left locked to exit with goroutine if netns is not restored
+	if err := netns.Set(origin); err != nil {
+		logger.Error("failed to restore netns: %v", err)
+		return err
+	}
+	runtime.UnlockOSThread()
+
+	return err
+}
+
+// list names of netns
+func listNetns() ([]string, error) {
+	entries, err := os.ReadDir(netnsPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// show netns with names of links in it
+func (s *server) ShowNetns(ctx context.Context, in *networker.NetnsQuery) (*networker.NetnsResponse, error) {
+	names, err := listNetns()
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	netnsList := make([]*networker.Netns, 0)
+	for _, name := range names {
+		if in.Name != "" && in.Name != name {
+			continue
+		}
+
+		links := make([]string, 0)
+		handle, err := getHandle(name)
+		if err != nil {
+			logger.Warn("%v\n", err)
+		} else {
+			linkList, err := handle.LinkList()
+			if err != nil {
+				logger.Warn("%v\n", err)
+			}
+			for _, link := range linkList {
+				links = append(links, link.Attrs().Name)
+			}
+			handle.Delete()
+		}
+
+		netnsList = append(netnsList, &networker.Netns{Name: name, Links: links})
+	}
+
+	return &networker.NetnsResponse{Netns: netnsList}, nil
+}
+
+// add named netns
+func (s *server) AddNetns(ctx context.Context, in *networker.NetnsQuery) (*networker.NetnsResponse, error) {
+	if err := checkNetnsName(in.Name); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	if _, err := os.Stat(path.Join(netnsPath, in.Name)); err == nil {
+		err = status.Errorf(codes.AlreadyExists, "netns \"%s\" already exists", in.Name)
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	if err := newNamedNetns(in.Name); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	// loopback of new netns is down
+	handle, err := getHandle(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	if lo, err := handle.LinkByName("lo"); err == nil {
+		handle.LinkSetUp(lo)
+	}
+
+	return &networker.NetnsResponse{Netns: []*networker.Netns{{Name: in.Name}}}, nil
+}
+
+// delete named netns. virtual links in netns are deleted and physical links
+// are returned to initial netns by kernel
+func (s *server) DelNetns(ctx context.Context, in *networker.NetnsQuery) (*networker.NetnsResponse, error) {
+	ns, err := getNetns(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	ns.Close()
+
+	if err := netns.DeleteNamed(in.Name); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.NetnsResponse{}, nil
+}
+
+// move link of netns of daemon into netns
+func (s *server) SetNetnsLink(ctx context.Context, in *networker.NetnsQuery) (*networker.NetnsResponse, error) {
+	link, err := netlink.LinkByName(in.LinkName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	ns, err := getNetns(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer ns.Close()
+
+	if err := netlink.LinkSetNsFd(link, int(ns)); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.NetnsResponse{}, nil
+}
+
+// move link of netns back to netns of daemon
+func (s *server) UnsetNetnsLink(ctx context.Context, in *networker.NetnsQuery) (*networker.NetnsResponse, error) {
+	handle, err := getHandle(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	link, err := handle.LinkByName(in.LinkName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	// netns of main thread is netns of daemon
+	origin, err := netns.GetFromPid(os.Getpid())
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer origin.Close()
+
+	if err := handle.LinkSetNsFd(link, int(origin)); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.NetnsResponse{}, nil
+}
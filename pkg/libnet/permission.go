@@ -0,0 +1,65 @@
+package libnet
+
+import "go-cli/pkg/libutil"
+
+// required role of networker methods
+var permissions = map[string]libutil.Role{
+	"ShowNetLink":     libutil.RoleViewer,
+	"ShowBridge":      libutil.RoleViewer,
+	"ShowBridgeSlave": libutil.RoleViewer,
+	"ShowBond":        libutil.RoleViewer,
+	"ShowBondSlave":   libutil.RoleViewer,
+	"ShowVeth":        libutil.RoleViewer,
+	"ShowVlan":        libutil.RoleViewer,
+	"ShowVxlan":       libutil.RoleViewer,
+	"ShowMacvlan":     libutil.RoleViewer,
+	"ShowIpvlan":      libutil.RoleViewer,
+	"ShowTuntap":      libutil.RoleViewer,
+	"ShowFdb":         libutil.RoleViewer,
+	"ShowNetns":       libutil.RoleViewer,
+	"ShowAddr":        libutil.RoleViewer,
+	"ShowRule":        libutil.RoleViewer,
+	"ShowRoute":       libutil.RoleViewer,
+	"ShowCandidate":   libutil.RoleViewer,
+
+	"SetNetLinkMac":     libutil.RoleOperator,
+	"SetNetLinkUp":      libutil.RoleOperator,
+	"SetNetLinkDown":    libutil.RoleOperator,
+	"SetBridgeMaster":   libutil.RoleOperator,
+	"UnsetBridgeMaster": libutil.RoleOperator,
+	"SetBondMaster":     libutil.RoleOperator,
+	"UnsetBondMaster":   libutil.RoleOperator,
+	"AddFdb":            libutil.RoleOperator,
+	"DelFdb":            libutil.RoleOperator,
+	"AddAddr":           libutil.RoleOperator,
+	"DelAddr":           libutil.RoleOperator,
+	"AddRule":           libutil.RoleOperator,
+	"DelRule":           libutil.RoleOperator,
+	"AddRoute":          libutil.RoleOperator,
+	"DelRoute":          libutil.RoleOperator,
+	"DiscardCandidate":  libutil.RoleOperator,
+	"Commit":            libutil.RoleOperator,
+	"ConfirmCommit":     libutil.RoleOperator,
+
+	"AddBridge":  libutil.RoleAdmin,
+	"DelBridge":  libutil.RoleAdmin,
+	"AddBond":    libutil.RoleAdmin,
+	"DelBond":    libutil.RoleAdmin,
+	"AddVeth":    libutil.RoleAdmin,
+	"DelVeth":    libutil.RoleAdmin,
+	"AddVlan":    libutil.RoleAdmin,
+	"DelVlan":    libutil.RoleAdmin,
+	"AddVxlan":   libutil.RoleAdmin,
+	"DelVxlan":   libutil.RoleAdmin,
+	"AddMacvlan": libutil.RoleAdmin,
+	"DelMacvlan": libutil.RoleAdmin,
+	"AddIpvlan":  libutil.RoleAdmin,
+	"DelIpvlan":  libutil.RoleAdmin,
+	"AddTuntap":  libutil.RoleAdmin,
+	"DelTuntap":  libutil.RoleAdmin,
+
+	"AddNetns":       libutil.RoleAdmin,
+	"DelNetns":       libutil.RoleAdmin,
+	"SetNetnsLink":   libutil.RoleAdmin,
+	"UnsetNetnsLink": libutil.RoleAdmin,
+}
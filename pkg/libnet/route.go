@@ -2,140 +2,410 @@ package libnet
 
 import (
 	"context"
+	"fmt"
 	"go-cli/pkg/libnet/networker"
 	"go-cli/pkg/libutil"
+	"math"
 	"net"
+	"strconv"
+	"strings"
 
 	"github.com/vishvananda/netlink"
 	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // show route
 func (s *server) ShowRoute(ctx context.Context, in *networker.RouteQuery) (*networker.RouteResponse, error) {
-	linkList, err := netlink.LinkList()
+	family, err := getFamily(in.Family)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	routeList := make([]*networker.Route, 0)
-	for _, link := range linkList {
-		routes, err := netlink.RouteListFiltered(unix.AF_INET,
-			&netlink.Route{LinkIndex: link.Attrs().Index}, netlink.RT_FILTER_OIF|netlink.RT_FILTER_TABLE)
-		if err != nil {
-			logger.Warn("%v\n", err)
-			return nil, err
-		}
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
 
-		for _, route := range routes {
-			logger.Info("%s %v %v %v", link.Attrs().Name, route, route.Protocol, route.Scope)
+	linkList, err := handle.LinkList()
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
 
-			if (route.Gw != nil && len(route.Gw) == net.IPv6len) || (route.Src != nil &&
-				len(route.Src) == net.IPv6len) || (route.Dst != nil && len(route.Dst.IP) == net.IPv6len) ||
-				route.Protocol == unix.RTPROT_KERNEL || in.Table != "" && route.Table != libutil.StringToUnixTableId(in.Table) {
-				continue
-			}
+	linkNames := make(map[int]string)
+	for _, link := range linkList {
+		linkNames[link.Attrs().Index] = link.Attrs().Name
+	}
+
+	// routes of all tables
+	routes, err := handle.RouteListFiltered(family, &netlink.Route{}, netlink.RT_FILTER_TABLE)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
 
-			destination := "default"
-			source := "any"
-			gateway := "any"
+	routeList := make([]*networker.Route, 0)
+	for _, route := range routes {
+		logger.Info("%v %v %v", route, route.Protocol, route.Scope)
 
-			if route.Dst != nil {
-				destination = route.Dst.String()
-			}
-			if route.Src != nil {
-				source = route.Src.String()
-			}
-			if route.Gw != nil {
-				gateway = route.Gw.String()
-			}
+		if route.Protocol == unix.RTPROT_KERNEL || in.Table != "" && route.Table != libutil.StringToUnixTableId(in.Table) {
+			continue
+		}
 
-			table := libutil.UnixTableIdToString(route.Table)
+		destination := "default"
+		source := "any"
+		gateway := "any"
+		device := linkNames[route.LinkIndex]
 
-			if in.Device == "" || in.Device == link.Attrs().Name {
+		if route.Dst != nil {
+			destination = route.Dst.String()
+		}
+		if route.Src != nil {
+			source = route.Src.String()
+		}
+		if route.Gw != nil {
+			gateway = route.Gw.String()
+		}
 
-				routeList = append(routeList, &networker.Route{
-					Protocol:    route.Protocol.String(),
-					Table:       table,
-					Destination: destination,
-					Source:      source,
-					NextHop:     gateway,
-					Device:      link.Attrs().Name,
-				})
+		// nexthops of multipath. eg. 10.0.0.1@eth0,10.0.1.1@eth1
+		devices := []string{device}
+		if len(route.MultiPath) > 0 {
+			nextHops := make([]string, 0)
+			devices = make([]string, 0)
+			for _, nextHop := range route.MultiPath {
+				nextHops = append(nextHops, fmt.Sprintf("%s@%s", nextHop.Gw, linkNames[nextHop.LinkIndex]))
+				devices = append(devices, linkNames[nextHop.LinkIndex])
 			}
+			gateway = strings.Join(nextHops, ",")
+			device = strings.Join(devices, ",")
 		}
 
+		if in.Device != "" && !libutil.Contains(devices, in.Device) {
+			continue
+		}
+
+		routeList = append(routeList, &networker.Route{
+			Protocol:    route.Protocol.String(),
+			Table:       libutil.UnixTableIdToString(route.Table),
+			Destination: destination,
+			Source:      source,
+			NextHop:     gateway,
+			Device:      device,
+			Family:      familyToString(route.Family),
+		})
 	}
 
-	return &networker.RouteResponse{Routes: routeList}, err
+	return &networker.RouteResponse{Routes: routeList}, nil
 }
 
-// add route
+// stage adding route to candidate
 func (s *server) AddRoute(ctx context.Context, in *networker.RouteQuery) (*networker.RouteResponse, error) {
-	var protocol = unix.RTPROT_STATIC
-	var dst *net.IPNet = nil
-	var nextHop net.IP = nil
-	var src net.IP = nil
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
 
-	table := libutil.StringToUnixTableId(in.Table)
-	if in.Destination != "" {
-		_, dst, _ = net.ParseCIDR(in.Destination)
+	if _, err := getNetlinkRoute(handle, in); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
 	}
-	if in.Source != "" {
-		src = net.ParseIP(in.Source)
+
+	s.stage(ctx, &networker.Change{Op: opAdd, Route: in})
+	return &networker.RouteResponse{}, nil
+}
+
+// stage deleting route to candidate
+func (s *server) DelRoute(ctx context.Context, in *networker.RouteQuery) (*networker.RouteResponse, error) {
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
+	if _, err := getNetlinkRoute(handle, in); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	s.stage(ctx, &networker.Change{Op: opDel, Route: in})
+	return &networker.RouteResponse{}, nil
+}
+
+// parse ip of nexthop with optional device. eg. fe80::1@eth0
+func parseNextHop(handle *netlink.Handle, nextHop string) (net.IP, int, error) {
+	ipString, device, hasDevice := strings.Cut(nextHop, "@")
+	ip := net.ParseIP(ipString)
+	if ip == nil {
+		return nil, 0, status.Errorf(codes.InvalidArgument, "invalid nexthop \"%s\"", nextHop)
+	}
+
+	if !hasDevice {
+		return ip, 0, nil
 	}
-	if in.NextHop != "" {
-		nextHop = net.ParseIP(in.NextHop)
+
+	link, err := handle.LinkByName(device)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ip, link.Attrs().Index, nil
+}
+
+// parse route protocol by name or number. eg. static, boot or 4. 0 if empty
+func parseRouteProtocol(protocol string) (netlink.RouteProtocol, error) {
+	if protocol == "" {
+		return 0, nil
+	}
+
+	if number, err := strconv.ParseUint(protocol, 10, 8); err == nil {
+		return netlink.RouteProtocol(number), nil
+	}
+
+	for number := 0; number <= math.MaxUint8; number++ {
+		if netlink.RouteProtocol(number).String() == protocol {
+			return netlink.RouteProtocol(number), nil
+		}
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "unknown route protocol \"%s\"", protocol)
+}
+
+// get netlink route by query. default destination is of family of nexthop, source or query
+func getNetlinkRoute(handle *netlink.Handle, in *networker.RouteQuery) (*netlink.Route, error) {
+	protocol, err := parseRouteProtocol(in.Protocol)
+	if err != nil {
+		return nil, err
 	}
 
 	route := &netlink.Route{
-		Protocol: netlink.RouteProtocol(protocol),
-		Table:    table,
-		Dst:      dst,
-		Src:      src,
-		Gw:       nextHop,
+		Protocol: protocol,
+		Table:    libutil.StringToUnixTableId(in.Table),
 	}
 
-	err := netlink.RouteAdd(route)
+	if in.Destination != "" && in.Destination != "default" {
+		_, dst, err := net.ParseCIDR(in.Destination)
+		if err != nil {
+			return nil, err
+		}
+		route.Dst = dst
+	}
+	if in.Source != "" && in.Source != "any" {
+		if route.Src = net.ParseIP(in.Source); route.Src == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid source \"%s\"", in.Source)
+		}
+	}
+	if in.Device != "" {
+		link, err := handle.LinkByName(in.Device)
+		if err != nil {
+			return nil, err
+		}
+		route.LinkIndex = link.Attrs().Index
+	}
+
+	if in.NextHop != "" && in.NextHop != "any" {
+		nextHops := strings.Split(in.NextHop, ",")
+		if len(nextHops) == 1 {
+			gw, linkIndex, err := parseNextHop(handle, nextHops[0])
+			if err != nil {
+				return nil, err
+			}
+			route.Gw = gw
+			if linkIndex != 0 {
+				route.LinkIndex = linkIndex
+			}
+		} else {
+			for _, nextHop := range nextHops {
+				gw, linkIndex, err := parseNextHop(handle, nextHop)
+				if err != nil {
+					return nil, err
+				}
+				route.MultiPath = append(route.MultiPath, &netlink.NexthopInfo{Gw: gw, LinkIndex: linkIndex})
+			}
+		}
+	}
+
+	if route.Dst == nil {
+		family := netlink.FAMILY_V4
+		switch {
+		case route.Gw != nil:
+			family = getIpFamily(route.Gw)
+		case len(route.MultiPath) > 0:
+			family = getIpFamily(route.MultiPath[0].Gw)
+		case route.Src != nil:
+			family = getIpFamily(route.Src)
+		case in.Family == "inet6":
+			family = netlink.FAMILY_V6
+		}
+
+		if family == netlink.FAMILY_V6 {
+			route.Dst = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+		} else {
+			route.Dst = &net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+		}
+	}
+
+	return route, nil
+}
+
+// add route. added route is returned to be deleted exactly.
+// protocol is static if not set
+func addRoute(in *networker.RouteQuery) (*netlink.Route, error) {
+	handle, err := getHandle(in.Netns)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
+	defer handle.Delete()
 
-	return &networker.RouteResponse{}, err
+	route, err := getNetlinkRoute(handle, in)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	if route.Protocol == 0 {
+		route.Protocol = unix.RTPROT_STATIC
+	}
+
+	err = handle.RouteAdd(route)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return route, nil
 }
 
-// del route
-func (s *server) DelRoute(ctx context.Context, in *networker.RouteQuery) (*networker.RouteResponse, error) {
-	var protocol = unix.RTPROT_STATIC
-	var dst *net.IPNet = nil
-	var nextHop net.IP = nil
-	var src net.IP = nil
+// is multipath of route same as nexthops of query
+func isSameMultiPath(multiPath []*netlink.NexthopInfo, nextHops []*netlink.NexthopInfo) bool {
+	if len(multiPath) != len(nextHops) {
+		return false
+	}
 
-	table := libutil.StringToUnixTableId(in.Table)
-	if in.Destination != "" {
-		_, dst, _ = net.ParseCIDR(in.Destination)
+	for i, nextHop := range nextHops {
+		if !multiPath[i].Gw.Equal(nextHop.Gw) ||
+			nextHop.LinkIndex != 0 && multiPath[i].LinkIndex != nextHop.LinkIndex {
+			return false
+		}
 	}
-	if in.Source != "" {
-		src = net.ParseIP(in.Source)
+	return true
+}
+
+// list routes matching query. nexthop, device, source and protocol match any
+// if not set. routes of kernel are not matched unless protocol is kernel
+func listMatchedRoutes(handle *netlink.Handle, in *networker.RouteQuery) ([]netlink.Route, error) {
+	route, err := getNetlinkRoute(handle, in)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := *route
+	filterMask := netlink.RT_FILTER_TABLE | netlink.RT_FILTER_DST
+	// listed default route has no destination
+	if ones, _ := route.Dst.Mask.Size(); ones == 0 {
+		filter.Dst = nil
 	}
-	if in.NextHop != "" {
-		nextHop = net.ParseIP(in.NextHop)
+	if route.Gw != nil {
+		filterMask |= netlink.RT_FILTER_GW
+	}
+	if route.LinkIndex != 0 && len(route.MultiPath) == 0 {
+		filterMask |= netlink.RT_FILTER_OIF
+	}
+	if route.Src != nil {
+		filterMask |= netlink.RT_FILTER_SRC
+	}
+	if route.Protocol != 0 {
+		filterMask |= netlink.RT_FILTER_PROTOCOL
 	}
 
-	route := &netlink.Route{
-		Protocol: netlink.RouteProtocol(protocol),
-		Table:    table,
-		Dst:      dst,
-		Src:      src,
-		Gw:       nextHop,
+	routes, err := handle.RouteListFiltered(getIpFamily(route.Dst.IP), &filter, filterMask)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]netlink.Route, 0)
+	for _, r := range routes {
+		if r.Protocol == unix.RTPROT_KERNEL && route.Protocol != unix.RTPROT_KERNEL {
+			continue
+		}
+		if len(route.MultiPath) > 0 && !isSameMultiPath(r.MultiPath, route.MultiPath) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+
+	if len(matched) == 0 {
+		return nil, status.Errorf(codes.NotFound, "route %s does not exist", route.Dst)
+	}
+	return matched, nil
+}
+
+// del routes matching query. deleted routes are returned to be added back
+func delRoute(in *networker.RouteQuery) ([]netlink.Route, error) {
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
 	}
+	defer handle.Delete()
 
-	err := netlink.RouteDel(route)
+	routes, err := listMatchedRoutes(handle, in)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	return &networker.RouteResponse{}, err
+	deleted := make([]netlink.Route, 0)
+	for i := range routes {
+		err = handle.RouteDel(&routes[i])
+		if err != nil {
+			logger.Warn("%v\n", err)
+			return deleted, err
+		}
+		deleted = append(deleted, routes[i])
+	}
+
+	return deleted, nil
+}
+
+// add netlink routes. eg. routes deleted by commit
+func addNetlinkRoutes(netnsName string, routes []netlink.Route) error {
+	handle, err := getHandle(netnsName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+	defer handle.Delete()
+
+	for i := range routes {
+		if err := handle.RouteAdd(&routes[i]); err != nil {
+			logger.Warn("%v\n", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// del netlink route exactly. eg. route added by commit
+func delNetlinkRoute(netnsName string, route *netlink.Route) error {
+	handle, err := getHandle(netnsName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+	defer handle.Delete()
+
+	err = handle.RouteDel(route)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+
+	return nil
 }
@@ -68,42 +68,98 @@ func parsePortRange(portRange string) (start, end uint16) {
 
 // show rule
 func (s *server) ShowRule(ctx context.Context, in *networker.RuleQuery) (*networker.RuleResponse, error) {
-	ruleListV4, err := netlink.RuleList(unix.AF_INET)
+	families, err := getFamilyList(in.Family)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
 	ruleList := make([]*networker.Rule, 0)
-	for _, rule := range ruleListV4 {
-		table := libutil.UnixTableIdToString(rule.Table)
+	for _, family := range families {
+		rules, err := handle.RuleList(family)
+		if err != nil {
+			logger.Warn("%v\n", err)
+			return nil, err
+		}
 
-		logger.Info("%v", rule)
-		if in.Table != "" && in.Table != table {
-			continue
+		for _, rule := range rules {
+			table := libutil.UnixTableIdToString(rule.Table)
+
+			logger.Info("%v", rule)
+			if in.Table != "" && in.Table != table {
+				continue
+			}
+
+			ruleList = append(ruleList, &networker.Rule{
+				Priority: int32(rule.Priority),
+				Table:    table,
+				Src:      ipNetToString(rule.Src),
+				Dst:      ipNetToString(rule.Dst),
+				SPort:    rulePortRangeToString(rule.Sport),
+				DPort:    rulePortRangeToString(rule.Dport),
+				IpProto:  ipProtoToString(rule.IPProto),
+				IIfName:  rule.IifName,
+				OIfName:  rule.OifName,
+				Family:   familyToString(family),
+			})
 		}
+	}
 
-		ruleList = append(ruleList, &networker.Rule{
-			Priority: int32(rule.Priority),
-			Table:    table,
-			Src:      ipNetToString(rule.Src),
-			Dst:      ipNetToString(rule.Dst),
-			SPort:    rulePortRangeToString(rule.Sport),
-			DPort:    rulePortRangeToString(rule.Dport),
-			IpProto:  ipProtoToString(rule.IPProto),
-			IIfName:  rule.IifName,
-			OIfName:  rule.OifName,
-		})
+	return &networker.RuleResponse{Rules: ruleList}, nil
+}
 
+// stage adding rule to candidate
+func (s *server) AddRule(ctx context.Context, in *networker.RuleQuery) (*networker.RuleResponse, error) {
+	if err := checkNetns(in.Netns); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
 	}
 
-	return &networker.RuleResponse{Rules: ruleList}, err
+	s.stage(ctx, &networker.Change{Op: opAdd, Rule: in})
+	return &networker.RuleResponse{}, nil
 }
 
-// add rule by query
-func (s *server) AddRule(ctx context.Context, in *networker.RuleQuery) (*networker.RuleResponse, error) {
+// stage deleting rule to candidate
+func (s *server) DelRule(ctx context.Context, in *networker.RuleQuery) (*networker.RuleResponse, error) {
+	if err := checkNetns(in.Netns); err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	s.stage(ctx, &networker.Change{Op: opDel, Rule: in})
+	return &networker.RuleResponse{}, nil
+}
+
+// get key of rule to find added rule. priority is assigned by kernel if not set
+func ruleKey(rule *netlink.Rule) string {
+	return fmt.Sprintf("%d %d %s %s %s %s %d %s %s %d %d %d %t", rule.Priority, rule.Table,
+		ipNetToString(rule.Src), ipNetToString(rule.Dst), rulePortRangeToString(rule.Sport),
+		rulePortRangeToString(rule.Dport), rule.IPProto, rule.IifName, rule.OifName,
+		rule.Mark, rule.Tos, rule.Goto, rule.Invert)
+}
+
+// add rule by query. added rule is returned as listed by kernel, so it is
+// deleted exactly without deleting rules of the same table
+func addRule(in *networker.RuleQuery) (*netlink.Rule, error) {
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
+
 	rule := netlink.NewRule()
 	rule.Table = libutil.StringToUnixTableId(in.Table)
+	if in.Priority != 0 {
+		rule.Priority = int(in.Priority)
+	}
 	logger.Info("in %v", in)
 
 	if in.Src != "any" {
@@ -114,6 +170,18 @@ func (s *server) AddRule(ctx context.Context, in *networker.RuleQuery) (*network
 		_, rule.Dst, _ = net.ParseCIDR(in.Dst)
 	}
 
+	// family of src and dst, or of query if both are any
+	switch {
+	case rule.Src != nil:
+		rule.Family = getIpFamily(rule.Src.IP)
+	case rule.Dst != nil:
+		rule.Family = getIpFamily(rule.Dst.IP)
+	case in.Family == "inet6":
+		rule.Family = netlink.FAMILY_V6
+	default:
+		rule.Family = netlink.FAMILY_V4
+	}
+
 	if in.SPort != "any" {
 		start, end := parsePortRange(in.SPort)
 		rule.Sport = &netlink.RulePortRange{
@@ -145,59 +213,119 @@ func (s *server) AddRule(ctx context.Context, in *networker.RuleQuery) (*network
 		}
 	}
 
-	err := netlink.RuleAdd(rule)
+	before, err := handle.RuleList(rule.Family)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
+	existing := make(map[string]bool)
+	for i := range before {
+		existing[ruleKey(&before[i])] = true
+	}
 
-	return &networker.RuleResponse{}, err
-}
-
-// del rule by query
-func (s *server) DelRule(ctx context.Context, in *networker.RuleQuery) (*networker.RuleResponse, error) {
-	ruleListV4, err := netlink.RuleList(netlink.FAMILY_V4)
+	err = handle.RuleAdd(rule)
 	if err != nil {
 		logger.Warn("%v\n", err)
 		return nil, err
 	}
 
-	for _, rule := range ruleListV4 {
-		table := libutil.StringToUnixTableId(in.Table)
-		if table != rule.Table {
-			continue
+	after, err := handle.RuleList(rule.Family)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	for i := range after {
+		if !existing[ruleKey(&after[i])] {
+			// listed rule has no family
+			after[i].Family = rule.Family
+			return &after[i], nil
 		}
+	}
 
-		if in.Priority != 0 && in.Priority != int32(rule.Priority) {
-			continue
-		}
+	return nil, fmt.Errorf("added rule is not listed")
+}
 
-		if in.Src != "any" && in.Src != ipNetToString(rule.Src) {
-			continue
-		}
+// delete rule exactly. eg. rule added by commit
+func delNetlinkRule(netnsName string, rule *netlink.Rule) error {
+	handle, err := getHandle(netnsName)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
+	defer handle.Delete()
 
-		if in.Dst != "any" && in.Dst != ipNetToString(rule.Dst) {
-			continue
-		}
+	err = handle.RuleDel(rule)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return err
+	}
 
-		if in.SPort != "any" && in.SPort != rulePortRangeToString(rule.Sport) {
-			continue
-		}
+	return nil
+}
 
-		if in.DPort != "any" && in.DPort != rulePortRangeToString(rule.Dport) {
-			continue
-		}
+// del rules matching query. deleted rules are returned
+func delRule(in *networker.RuleQuery) ([]netlink.Rule, error) {
+	families, err := getFamilyList(in.Family)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
 
-		if in.IpProto != "any" && in.IpProto != ipProtoToString(rule.IPProto) {
-			continue
-		}
+	handle, err := getHandle(in.Netns)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	defer handle.Delete()
 
-		err = netlink.RuleDel(&rule)
+	deleted := make([]netlink.Rule, 0)
+	for _, family := range families {
+		rules, err := handle.RuleList(family)
 		if err != nil {
 			logger.Warn("%v\n", err)
-			return nil, err
+			return deleted, err
+		}
+
+		for _, rule := range rules {
+			table := libutil.StringToUnixTableId(in.Table)
+			if table != rule.Table {
+				continue
+			}
+
+			if in.Priority != 0 && in.Priority != int32(rule.Priority) {
+				continue
+			}
+
+			if in.Src != "any" && in.Src != ipNetToString(rule.Src) {
+				continue
+			}
+
+			if in.Dst != "any" && in.Dst != ipNetToString(rule.Dst) {
+				continue
+			}
+
+			if in.SPort != "any" && in.SPort != rulePortRangeToString(rule.Sport) {
+				continue
+			}
+
+			if in.DPort != "any" && in.DPort != rulePortRangeToString(rule.Dport) {
+				continue
+			}
+
+			if in.IpProto != "any" && in.IpProto != ipProtoToString(rule.IPProto) {
+				continue
+			}
+
+			// listed rule has no family
+			rule.Family = family
+			err = handle.RuleDel(&rule)
+			if err != nil {
+				logger.Warn("%v\n", err)
+				return deleted, err
+			}
+			deleted = append(deleted, rule)
 		}
 	}
 
-	return &networker.RuleResponse{}, err
+	return deleted, nil
 }
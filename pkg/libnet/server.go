@@ -1,50 +1,100 @@
 package libnet
 
 import (
-	"fmt"
+	"errors"
 	"go-cli/pkg/libnet/networker"
 	"go-cli/pkg/libutil"
 	"log"
-	"net"
-	"os"
+	"sync"
+	"time"
 
 	nblogger "github.com/banaconda/nb-logger"
+	"github.com/vishvananda/netlink"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 )
 
 var logger nblogger.Logger
 
+// audit db of rollback of unconfirmed commit
+var auditDB *libutil.AuditDB
+
+// grpc server is set by serving goroutine and stopped by others
+var grpcServer *grpc.Server
+var grpcServerMutex sync.Mutex
+
 type server struct {
 	networker.UnimplementedNetworkerServer
+
+	// candidate config
+	mutex         sync.Mutex
+	changes       []*networker.Change
+	confirmUndo   []func() error
+	confirmTimer  *time.Timer
+	confirmNumber int
+}
+
+// get grpc code of netlink error. codes of errno are got by libutil
+func getErrorCode(err error) codes.Code {
+	var linkNotFound netlink.LinkNotFoundError
+	if errors.As(err, &linkNotFound) {
+		return codes.NotFound
+	}
+
+	return codes.Unknown
 }
 
-func handlerRequests(host string, port int) {
-	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+func handlerRequests(network string, address string, opts ...grpc.ServerOption) {
+	lis, err := libutil.Listen(network, address)
 	if err != nil {
 		logger.Error("failed to listen: %v", err)
 		panic(err)
 	}
 
-	s := grpc.NewServer()
+	newServer := grpc.NewServer(opts...)
+	networker.RegisterNetworkerServer(newServer, &server{})
+	grpcServerMutex.Lock()
+	grpcServer = newServer
+	grpcServerMutex.Unlock()
 
-	networker.RegisterNetworkerServer(s, &server{})
 	logger.Info("server listening at %v", lis.Addr())
-	if err := s.Serve(lis); err != nil {
+	if err := newServer.Serve(lis); err != nil {
 		logger.Error("failed to serve: %v", err)
 		panic(err)
 	}
 }
 
-func NetServer() {
-	netLogPath := "log/net.log"
-	if _, err := os.Stat("log/"); os.IsNotExist(err) {
-		os.MkdirAll("log", 0700)
-	}
+// serve networker on address of config. eg. unix /run/go-cli/net.sock
+// mutating calls are recorded to audit db
+func NetServer(config *libutil.Config, audit *libutil.AuditDB) {
+	auditDB = audit
 
 	var err error
-	logger, err = nblogger.NewLogger(netLogPath, nblogger.Info, 1000, nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
+	logger, err = nblogger.NewLogger(config.GetLogPath("net.log"), nblogger.Info, 1000, nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
 	if err != nil {
 		log.Fatalf("logger init fail: %v", err)
 	}
-	handlerRequests("", libutil.NET_PORT)
+
+	opts, err := config.GetServerOptions()
+	if err != nil {
+		logger.Error("failed to load tls: %v", err)
+		return
+	}
+	opts = append(opts, audit.GetAuditOptions(logger, config)...)
+	opts = append(opts, config.GetAuthorizeOptions(logger, permissions)...)
+	opts = append(opts, libutil.GetStatusOptions(getErrorCode)...)
+
+	network, address := config.GetNetAddress()
+	handlerRequests(network, address, opts...)
+}
+
+// stop NetServer gracefully after pending requests are done
+func NetServerStop() {
+	grpcServerMutex.Lock()
+	stopServer := grpcServer
+	grpcServerMutex.Unlock()
+
+	if stopServer != nil {
+		stopServer.GracefulStop()
+	}
 }
@@ -0,0 +1,180 @@
+package libnet
+
+import (
+	"context"
+	"go-cli/pkg/libnet/networker"
+	"os"
+	"os/user"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// path of links in sysfs. netlink does not report multi queue and unset owner of tuntap
+const sysfsNetPath = "/sys/class/net"
+
+// get uid of owner of tuntap. owner is user name or uid. root if empty,
+// since netlink always sets owner and group of tuntap
+func parseTuntapOwner(owner string) (uint32, error) {
+	if owner == "" {
+		return 0, nil
+	}
+
+	if uid, err := strconv.ParseUint(owner, 10, 32); err == nil {
+		return uint32(uid), nil
+	}
+
+	u, err := user.Lookup(owner)
+	if err != nil {
+		return 0, err
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(uid), nil
+}
+
+// get user name of uid. uid if user does not exist
+func uidToString(uid int64) string {
+	u, err := user.LookupId(strconv.FormatInt(uid, 10))
+	if err != nil {
+		return strconv.FormatInt(uid, 10)
+	}
+	return u.Username
+}
+
+// read integer attribute of link from sysfs. eg. tun_flags
+func readSysfsInt(name string, attr string) (int64, error) {
+	data, err := os.ReadFile(path.Join(sysfsNetPath, name, attr))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 0, 64)
+}
+
+// get tuntap of netlink tuntap. owner and multi queue are read from sysfs,
+// owner falls back to netlink if sysfs is not of netns of daemon
+func getTuntap(tuntap *netlink.Tuntap) *networker.Tuntap {
+	owner := uidToString(int64(tuntap.Owner))
+	if uid, err := readSysfsInt(tuntap.Name, "owner"); err == nil {
+		owner = ""
+		if uid >= 0 {
+			owner = uidToString(uid)
+		}
+	}
+
+	multiQueue := false
+	if flags, err := readSysfsInt(tuntap.Name, "tun_flags"); err == nil {
+		multiQueue = flags&unix.IFF_MULTI_QUEUE != 0
+	}
+
+	return &networker.Tuntap{
+		Name:       tuntap.Name,
+		Mode:       tuntap.Mode.String(),
+		Owner:      owner,
+		MultiQueue: multiQueue,
+		Mac:        tuntap.HardwareAddr.String(),
+		Status:     tuntap.OperState.String(),
+	}
+}
+
+// get netlink tuntap by query. tuntap persists after fds are closed
+func getNetlinkTuntap(in *networker.TuntapQuery) (*netlink.Tuntap, error) {
+	mode, ok := netlink.StringToTuntapModeMap[in.Mode]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown tuntap mode \"%s\"", in.Mode)
+	}
+
+	owner, err := parseTuntapOwner(in.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	linkAttrs := netlink.NewLinkAttrs()
+	linkAttrs.Name = in.Name
+	tuntap := &netlink.Tuntap{
+		LinkAttrs: linkAttrs,
+		Mode:      mode,
+		Queues:    1,
+		Owner:     owner,
+	}
+
+	switch in.MultiQueue {
+	case "", "off":
+		tuntap.Flags = netlink.TUNTAP_NO_PI
+	case "on":
+		tuntap.Flags = netlink.TUNTAP_MULTI_QUEUE_DEFAULTS
+	default:
+		return nil, status.Error(codes.InvalidArgument, "multi queue must be on or off")
+	}
+
+	return tuntap, nil
+}
+
+// show tuntaps filtered by name
+func (s *server) ShowTuntap(ctx context.Context, in *networker.TuntapQuery) (*networker.TuntapResponse, error) {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	tuntapList := make([]*networker.Tuntap, 0)
+	for _, link := range linkList {
+		tuntap, ok := link.(*netlink.Tuntap)
+		if !ok || (in.Name != "" && in.Name != tuntap.Name) {
+			continue
+		}
+		tuntapList = append(tuntapList, getTuntap(tuntap))
+	}
+
+	return &networker.TuntapResponse{Tuntaps: tuntapList}, nil
+}
+
+// add persistent tuntap. queues are attached later by users of tuntap. eg. qemu
+func (s *server) AddTuntap(ctx context.Context, in *networker.TuntapQuery) (*networker.TuntapResponse, error) {
+	newTuntap, err := getNetlinkTuntap(in)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = addLink(newTuntap)
+	for _, fd := range newTuntap.Fds {
+		fd.Close()
+	}
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return s.ShowTuntap(ctx, &networker.TuntapQuery{Name: in.Name})
+}
+
+func (s *server) DelTuntap(ctx context.Context, in *networker.TuntapQuery) (*networker.TuntapResponse, error) {
+	link, err := netlink.LinkByName(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	if _, ok := link.(*netlink.Tuntap); !ok {
+		err = status.Errorf(codes.InvalidArgument, "link \"%s\" is not a tuntap", in.Name)
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.LinkDel(link)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.TuntapResponse{}, nil
+}
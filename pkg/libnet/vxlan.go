@@ -0,0 +1,349 @@
+package libnet
+
+import (
+	"context"
+	"go-cli/pkg/libnet/networker"
+	"net"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// iana port of vxlan
+const vxlanPort = 4789
+
+// vni is 24 bit
+const maxVni = 1<<24 - 1
+
+// mac of fdb entry flooding to remote vtep
+const zeroMac = "00:00:00:00:00:00"
+
+// parse ip of vxlan option. nil if empty
+func parseVxlanIp(name string, ipString string) (net.IP, error) {
+	if ipString == "" {
+		return nil, nil
+	}
+
+	ip := net.ParseIP(ipString)
+	if ip == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid %s \"%s\"", name, ipString)
+	}
+	return ip, nil
+}
+
+// get netlink vxlan by query
+func getNetlinkVxlan(in *networker.VxlanQuery) (*netlink.Vxlan, error) {
+	if in.Vni < 0 || in.Vni > maxVni {
+		return nil, status.Errorf(codes.InvalidArgument, "vni is out of range 0-%d", maxVni)
+	}
+
+	linkAttrs := netlink.NewLinkAttrs()
+	linkAttrs.Name = in.Name
+	vxlan := &netlink.Vxlan{
+		LinkAttrs: linkAttrs,
+		VxlanId:   int(in.Vni),
+		Port:      int(in.DstPort),
+	}
+
+	if vxlan.Port == 0 {
+		vxlan.Port = vxlanPort
+	}
+	if vxlan.Port < 0 || vxlan.Port > 65535 {
+		return nil, status.Error(codes.InvalidArgument, "dst port is out of range 1-65535")
+	}
+
+	switch in.Learning {
+	case "", "on":
+		vxlan.Learning = true
+	case "off":
+		vxlan.Learning = false
+	default:
+		return nil, status.Error(codes.InvalidArgument, "learning must be on or off")
+	}
+
+	var err error
+	if vxlan.SrcAddr, err = parseVxlanIp("local", in.Local); err != nil {
+		return nil, err
+	}
+
+	// remote and group are both group of netlink
+	if in.Remote != "" && in.Group != "" {
+		return nil, status.Error(codes.InvalidArgument, "remote and group are exclusive")
+	}
+	if in.Remote != "" {
+		if vxlan.Group, err = parseVxlanIp("remote", in.Remote); err != nil {
+			return nil, err
+		}
+		if vxlan.Group.IsMulticast() {
+			return nil, status.Errorf(codes.InvalidArgument, "remote \"%s\" is multicast, use group", in.Remote)
+		}
+	}
+	if in.Group != "" {
+		if vxlan.Group, err = parseVxlanIp("group", in.Group); err != nil {
+			return nil, err
+		}
+		if !vxlan.Group.IsMulticast() {
+			return nil, status.Errorf(codes.InvalidArgument, "group \"%s\" is not multicast", in.Group)
+		}
+		if in.Device == "" {
+			return nil, status.Error(codes.InvalidArgument, "group requires device")
+		}
+	}
+
+	if vxlan.SrcAddr != nil && vxlan.Group != nil && getIpFamily(vxlan.SrcAddr) != getIpFamily(vxlan.Group) {
+		return nil, status.Error(codes.InvalidArgument, "local and remote must be of same family")
+	}
+
+	if in.Device != "" {
+		device, err := netlink.LinkByName(in.Device)
+		if err != nil {
+			return nil, err
+		}
+		vxlan.VtepDevIndex = device.Attrs().Index
+	}
+
+	return vxlan, nil
+}
+
+// list vxlans
+func listVxlan() ([]*networker.Vxlan, error) {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	linkNames := make(map[int]string)
+	for _, link := range linkList {
+		linkNames[link.Attrs().Index] = link.Attrs().Name
+	}
+
+	vxlanList := make([]*networker.Vxlan, 0)
+	for _, link := range linkList {
+		vxlan, ok := link.(*netlink.Vxlan)
+		if !ok {
+			continue
+		}
+
+		local := ""
+		if vxlan.SrcAddr != nil {
+			local = vxlan.SrcAddr.String()
+		}
+		remote := ""
+		if vxlan.Group != nil {
+			remote = vxlan.Group.String()
+		}
+
+		vxlanList = append(vxlanList, &networker.Vxlan{
+			Name:     vxlan.Name,
+			Vni:      int32(vxlan.VxlanId),
+			Local:    local,
+			Remote:   remote,
+			DstPort:  int32(vxlan.Port),
+			Learning: vxlan.Learning,
+			Device:   linkNames[vxlan.VtepDevIndex],
+			Mac:      vxlan.HardwareAddr.String(),
+			Status:   vxlan.OperState.String(),
+		})
+	}
+
+	return vxlanList, nil
+}
+
+func (s *server) ShowVxlan(ctx context.Context, in *networker.VxlanQuery) (*networker.VxlanResponse, error) {
+	vxlanList, err := listVxlan()
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	filtered := make([]*networker.Vxlan, 0)
+	for _, vxlan := range vxlanList {
+		if (in.Name == "" || in.Name == vxlan.Name) && (in.Vni == 0 || in.Vni == vxlan.Vni) {
+			filtered = append(filtered, vxlan)
+		}
+	}
+
+	return &networker.VxlanResponse{Vxlans: filtered}, nil
+}
+
+func (s *server) AddVxlan(ctx context.Context, in *networker.VxlanQuery) (*networker.VxlanResponse, error) {
+	newVxlan, err := getNetlinkVxlan(in)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.LinkAdd(newVxlan)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	link, err := netlink.LinkByName(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+	netlink.LinkSetUp(link)
+
+	return s.ShowVxlan(ctx, &networker.VxlanQuery{Name: in.Name})
+}
+
+func (s *server) DelVxlan(ctx context.Context, in *networker.VxlanQuery) (*networker.VxlanResponse, error) {
+	link, err := netlink.LinkByName(in.Name)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	if _, ok := link.(*netlink.Vxlan); !ok {
+		err = status.Errorf(codes.InvalidArgument, "link \"%s\" is not a vxlan", in.Name)
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.LinkDel(link)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.VxlanResponse{}, nil
+}
+
+// get string of state of fdb entry
+func fdbStateToString(state int) string {
+	switch {
+	case state&netlink.NUD_PERMANENT != 0:
+		return "permanent"
+	case state&netlink.NUD_NOARP != 0:
+		return "static"
+	case state&netlink.NUD_REACHABLE != 0:
+		return "reachable"
+	case state&netlink.NUD_STALE != 0:
+		return "stale"
+	default:
+		return "none"
+	}
+}
+
+// get netlink neigh of static fdb entry by query. mac is zero mac if empty
+func getFdbNeigh(in *networker.FdbQuery) (*netlink.Neigh, error) {
+	link, err := netlink.LinkByName(in.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	macString := in.Mac
+	if macString == "" {
+		macString = zeroMac
+	}
+	mac, err := net.ParseMAC(macString)
+	if err != nil {
+		return nil, err
+	}
+
+	dst, err := parseVxlanIp("dst", in.Dst)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := link.(*netlink.Vxlan); ok && dst == nil {
+		return nil, status.Error(codes.InvalidArgument, "dst is required by vxlan")
+	}
+
+	return &netlink.Neigh{
+		LinkIndex:    link.Attrs().Index,
+		Family:       unix.AF_BRIDGE,
+		State:        netlink.NUD_PERMANENT,
+		Flags:        netlink.NTF_SELF,
+		HardwareAddr: mac,
+		IP:           dst,
+	}, nil
+}
+
+// show fdb entries. entries of all devices if name is empty
+func (s *server) ShowFdb(ctx context.Context, in *networker.FdbQuery) (*networker.FdbResponse, error) {
+	linkList, err := netlink.LinkList()
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	linkIndex := 0
+	linkNames := make(map[int]string)
+	for _, link := range linkList {
+		linkNames[link.Attrs().Index] = link.Attrs().Name
+		if link.Attrs().Name == in.Name {
+			linkIndex = link.Attrs().Index
+		}
+	}
+	if in.Name != "" && linkIndex == 0 {
+		err = status.Errorf(codes.NotFound, "link \"%s\" does not exist", in.Name)
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	neighs, err := netlink.NeighList(linkIndex, unix.AF_BRIDGE)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	fdbList := make([]*networker.Fdb, 0)
+	for _, neigh := range neighs {
+		if in.Name != "" && neigh.LinkIndex != linkIndex {
+			continue
+		}
+
+		dst := ""
+		if neigh.IP != nil {
+			dst = neigh.IP.String()
+		}
+
+		fdbList = append(fdbList, &networker.Fdb{
+			Name:   linkNames[neigh.LinkIndex],
+			Mac:    neigh.HardwareAddr.String(),
+			Dst:    dst,
+			Vni:    int32(neigh.VNI),
+			Master: linkNames[neigh.MasterIndex],
+			State:  fdbStateToString(neigh.State),
+		})
+	}
+
+	return &networker.FdbResponse{Fdbs: fdbList}, nil
+}
+
+// append static fdb entry. entries of zero mac to remote vteps are flooded
+func (s *server) AddFdb(ctx context.Context, in *networker.FdbQuery) (*networker.FdbResponse, error) {
+	neigh, err := getFdbNeigh(in)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.NeighAppend(neigh)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.FdbResponse{}, nil
+}
+
+func (s *server) DelFdb(ctx context.Context, in *networker.FdbQuery) (*networker.FdbResponse, error) {
+	neigh, err := getFdbNeigh(in)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	err = netlink.NeighDel(neigh)
+	if err != nil {
+		logger.Warn("%v\n", err)
+		return nil, err
+	}
+
+	return &networker.FdbResponse{}, nil
+}
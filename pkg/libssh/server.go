@@ -0,0 +1,285 @@
+package libssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"go-cli/pkg/libcli"
+	"go-cli/pkg/libutil"
+	"log"
+	"net"
+	"os"
+	"sync"
+
+	nblogger "github.com/banaconda/nb-logger"
+	"golang.org/x/crypto/ssh"
+)
+
+var logger nblogger.Logger
+
+// public keys allowed to log in. comment of each key is its user
+var authorizedKeysPath string
+
+// listener is set by serving goroutine and closed by others
+var listener net.Listener
+var listenerMutex sync.Mutex
+
+// payload of pty-req request
+type ptyRequest struct {
+	Term        string
+	Width       uint32
+	Height      uint32
+	PixelWidth  uint32
+	PixelHeight uint32
+	Modes       string
+}
+
+// payload of window-change request
+type windowChangeRequest struct {
+	Width       uint32
+	Height      uint32
+	PixelWidth  uint32
+	PixelHeight uint32
+}
+
+// payload of exit-status request
+type exitStatusRequest struct {
+	Status uint32
+}
+
+// load host key. generate ed25519 key if not exist
+func loadHostKey(path string) (ssh.Signer, error) {
+	if !libutil.IsExist(path) {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, err
+		}
+		logger.Info("host key generated at %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.ParsePrivateKey(data)
+}
+
+// public key and user of authorized_keys entry
+type authorizedKey struct {
+	publicKey ssh.PublicKey
+	user      string
+}
+
+// parse public keys of authorized_keys format. user of key is its comment
+func parseAuthorizedKeys(data []byte) []authorizedKey {
+	authorizedKeys := make([]authorizedKey, 0)
+	for len(bytes.TrimSpace(data)) > 0 {
+		publicKey, comment, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			break
+		}
+		authorizedKeys = append(authorizedKeys, authorizedKey{publicKey: publicKey, user: comment})
+		data = rest
+	}
+
+	return authorizedKeys
+}
+
+// get keys of authorized_keys file
+func getAuthorizedKeys() ([]authorizedKey, error) {
+	data, err := os.ReadFile(authorizedKeysPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAuthorizedKeys(data), nil
+}
+
+// authenticate public key of user. key is allowed only for user of its comment,
+// since user is forwarded as identity of calls to networker and vmer
+func checkPublicKey(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	authorizedKeys, err := getAuthorizedKeys()
+	if err != nil {
+		logger.Warn("failed to read authorized keys: %v", err)
+		return nil, err
+	}
+
+	for _, authorizedKey := range authorizedKeys {
+		if !bytes.Equal(authorizedKey.publicKey.Marshal(), key.Marshal()) {
+			continue
+		}
+
+		// local is identity of unix socket peer
+		if authorizedKey.user != conn.User() || conn.User() == libutil.IdentityLocal {
+			return nil, fmt.Errorf("public key is not allowed for %s", conn.User())
+		}
+		return &ssh.Permissions{
+			Extensions: map[string]string{"pubkey-fp": ssh.FingerprintSHA256(key)},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown public key for %s", conn.User())
+}
+
+// run cli on session until cli quits or session is closed
+func handleSession(channel ssh.Channel, requests <-chan *ssh.Request, user string,
+	newCli func(user string) (*libcli.GoCli, error)) {
+	defer channel.Close()
+
+	cli, err := newCli(user)
+	if err != nil {
+		logger.Warn("failed to init cli of %s: %v", user, err)
+		fmt.Fprintf(channel.Stderr(), "%v\r\n", err)
+		channel.SendRequest("exit-status", false, ssh.Marshal(&exitStatusRequest{Status: 1}))
+		return
+	}
+	cli.SetTerminal(channel, channel)
+
+	isRunning := false
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			var pty ptyRequest
+			if err := ssh.Unmarshal(req.Payload, &pty); err != nil {
+				logger.Warn("%v", err)
+				req.Reply(false, nil)
+				continue
+			}
+			cli.SetWindowHeight(int(pty.Height))
+			req.Reply(true, nil)
+		case "window-change":
+			var window windowChangeRequest
+			if err := ssh.Unmarshal(req.Payload, &window); err != nil {
+				logger.Warn("%v", err)
+				continue
+			}
+			cli.SetWindowHeight(int(window.Height))
+		case "shell":
+			if isRunning {
+				req.Reply(false, nil)
+				continue
+			}
+			isRunning = true
+			req.Reply(true, nil)
+
+			go func() {
+				cli.Run()
+				channel.SendRequest("exit-status", false, ssh.Marshal(&exitStatusRequest{Status: 0}))
+				channel.Close()
+			}()
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+// handle channels of ssh connection
+func handleConn(nConn net.Conn, config *ssh.ServerConfig, newCli func(user string) (*libcli.GoCli, error)) {
+	defer nConn.Close()
+
+	conn, channels, requests, err := ssh.NewServerConn(nConn, config)
+	if err != nil {
+		logger.Warn("failed to handshake: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	logger.Info("%s logged in from %s by %s", conn.User(), conn.RemoteAddr(),
+		conn.Permissions.Extensions["pubkey-fp"])
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			logger.Warn("failed to accept channel: %v", err)
+			continue
+		}
+
+		go handleSession(channel, requests, conn.User(), newCli)
+	}
+
+	logger.Info("%s logged out from %s", conn.User(), conn.RemoteAddr())
+}
+
+func handlerRequests(host string, port int, config *ssh.ServerConfig,
+	newCli func(user string) (*libcli.GoCli, error)) {
+	newListener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+	if err != nil {
+		logger.Error("failed to listen: %v", err)
+		return
+	}
+	listenerMutex.Lock()
+	listener = newListener
+	listenerMutex.Unlock()
+
+	logger.Info("server listening at %v", newListener.Addr())
+	for {
+		nConn, err := newListener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			logger.Warn("failed to accept: %v", err)
+			continue
+		}
+
+		go handleConn(nConn, config, newCli)
+	}
+}
+
+// serve cli over ssh. newCli makes cli of each session for user of session
+func SshServer(config *libutil.Config, newCli func(user string) (*libcli.GoCli, error)) {
+	var err error
+	logger, err = nblogger.NewLogger(config.GetLogPath("ssh.log"), nblogger.Info, 1000, nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
+	if err != nil {
+		log.Fatalf("logger init fail: %v", err)
+	}
+
+	hostKey, err := loadHostKey(config.SshHostKey)
+	if err != nil {
+		logger.Error("failed to load host key: %v", err)
+		return
+	}
+	if config.SshAuthorizedKeys == "" {
+		logger.Error("ssh_authorized_keys: required by ssh server")
+		return
+	}
+	authorizedKeysPath = config.SshAuthorizedKeys
+
+	sshConfig := &ssh.ServerConfig{
+		PublicKeyCallback: checkPublicKey,
+	}
+	sshConfig.AddHostKey(hostKey)
+
+	handlerRequests(config.Host, config.SshPort, sshConfig, newCli)
+}
+
+// stop accepting ssh connections
+func SshServerStop() {
+	listenerMutex.Lock()
+	defer listenerMutex.Unlock()
+
+	if listener != nil {
+		listener.Close()
+	}
+}
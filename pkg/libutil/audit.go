@@ -0,0 +1,166 @@
+package libutil
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	nblogger "github.com/banaconda/nb-logger"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// verbs of methods. longer verb is matched first. eg. Delete before Del
+var auditVerbs = []string{"Show", "Add", "Delete", "Del", "Set", "Unset", "Create", "Upload",
+	"Start", "Stop", "Update", "Discard", "Confirm"}
+
+var upperRegexp = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// audit log of mutating grpc call
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	Identity  string
+	Service   string
+	Method    string
+	Resource  string `gorm:"index"`
+	Request   string
+	Result    string
+}
+
+type AuditDB struct {
+	db *gorm.DB
+}
+
+// get dsn of sqlite db by path. db is shared by audit and vmer,
+// so busy db is waited instead of failing
+func GetSqliteDsn(path string) string {
+	return path + "?_busy_timeout=5000"
+}
+
+// open audit db by path. audit logs are stored in table of sqlite db shared with vmer
+func NewAuditDB(path string) (*AuditDB, error) {
+	db, err := gorm.Open(sqlite.Open(GetSqliteDsn(path)), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.AutoMigrate(&AuditLog{}); err != nil {
+		return nil, err
+	}
+
+	return &AuditDB{db: db}, nil
+}
+
+// insert audit log
+func (auditDB *AuditDB) Insert(auditLog *AuditLog) error {
+	return auditDB.db.Create(auditLog).Error
+}
+
+// get audit logs since time in order of time. resource matches part of resource name
+func (auditDB *AuditDB) Find(since time.Time, resource string) ([]AuditLog, error) {
+	var auditLogs []AuditLog
+	tx := auditDB.db.Where("created_at >= ?", since)
+	if resource != "" {
+		tx = tx.Where("resource LIKE ?", "%"+resource+"%")
+	}
+
+	err := tx.Order("created_at").Find(&auditLogs).Error
+	if err != nil {
+		return nil, err
+	}
+	return auditLogs, nil
+}
+
+// get resource of method without verb. eg. SetNetLinkMac -> net-link-mac
+func getAuditResource(method string) string {
+	for _, verb := range auditVerbs {
+		if strings.HasPrefix(method, verb) && len(method) > len(verb) {
+			method = strings.TrimPrefix(method, verb)
+			break
+		}
+	}
+
+	return strings.ToLower(upperRegexp.ReplaceAllString(method, "$1-$2"))
+}
+
+// is method mutating. show methods are not audited
+func isMutatingMethod(method string) bool {
+	return !strings.HasPrefix(method, "Show")
+}
+
+type auditResultKey struct{}
+
+// set result of audit log of call instead of ok. eg. staged
+func SetAuditResult(ctx context.Context, result string) {
+	if auditResult, ok := ctx.Value(auditResultKey{}).(*string); ok {
+		*auditResult = result
+	}
+}
+
+// get grpc server options recording mutating unary calls with identity, request and result.
+// options are chained before authorization, so denied calls are recorded as well
+func (auditDB *AuditDB) GetAuditOptions(logger nblogger.Logger, config *Config) []grpc.ServerOption {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		method := path.Base(info.FullMethod)
+		if !isMutatingMethod(method) {
+			return handler(ctx, req)
+		}
+
+		// result is set by handler if not ok
+		result := "ok"
+		resp, err := handler(context.WithValue(ctx, auditResultKey{}, &result), req)
+
+		// identity is not authorized yet. peer is recorded if identity is invalid
+		identity, identityErr := config.getIdentity(ctx)
+		if identityErr != nil {
+			identity = GetPeerIdentity(ctx)
+		}
+
+		auditLog := &AuditLog{
+			Identity: identity,
+			Service:  strings.TrimPrefix(path.Dir(info.FullMethod), "/"),
+			Method:   method,
+			Resource: getAuditResource(method),
+			Result:   result,
+		}
+		if message, ok := req.(proto.Message); ok {
+			if data, err := protojson.Marshal(message); err == nil {
+				auditLog.Request = string(data)
+			}
+		}
+		if err != nil {
+			auditLog.Result = err.Error()
+		}
+
+		if err := auditDB.Insert(auditLog); err != nil {
+			logger.Warn("failed to insert audit log: %v", err)
+		}
+
+		return resp, err
+	}
+
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary)}
+}
+
+// parse time of since. duration is before now. eg. 1h, 2006-01-02, 2006-01-02T15:04:05
+func ParseSince(arg string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(arg); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, arg, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("not a duration or time. eg. 1h, 2006-01-02, 2006-01-02T15:04:05")
+}
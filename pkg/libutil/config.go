@@ -0,0 +1,303 @@
+package libutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// prefix of env vars overriding config. eg. GOCLI_NET_PORT=20000
+const configEnvPrefix = "GOCLI_"
+
+// config of daemon and cli
+type Config struct {
+	// listen on tcp ports of host instead of unix sockets
+	Tcp       bool   `yaml:"tcp"`
+	Host      string `yaml:"host"`
+	NetPort   int    `yaml:"net_port"`
+	VmPort    int    `yaml:"vm_port"`
+	SshPort   int    `yaml:"ssh_port"`
+	HttpPort  int    `yaml:"http_port"`
+	NetSocket string `yaml:"net_socket"`
+	VmSocket  string `yaml:"vm_socket"`
+
+	DbPath string `yaml:"db_path"`
+	LogDir string `yaml:"log_dir"`
+	// ssh host key is generated if not exist. ssh users log in by keys of
+	// authorized keys, whose comment is user of key
+	SshHostKey        string `yaml:"ssh_host_key"`
+	SshAuthorizedKeys string `yaml:"ssh_authorized_keys"`
+
+	VolumeDir  string `yaml:"volume_dir"`
+	LibvirtUri string `yaml:"libvirt_uri"`
+	ImageMaker string `yaml:"image_maker"`
+
+	// tls of grpc services. clients are verified by ca if set (mutual tls)
+	Tls           bool   `yaml:"tls"`
+	TlsCert       string `yaml:"tls_cert"`
+	TlsKey        string `yaml:"tls_key"`
+	TlsCa         string `yaml:"tls_ca"`
+	TlsClientCert string `yaml:"tls_client_cert"`
+	TlsClientKey  string `yaml:"tls_client_key"`
+	TlsServerName string `yaml:"tls_server_name"`
+
+	// role based authorization of grpc calls. roles map identity to role,
+	// tokens map token to identity. token is sent by cli
+	Rbac        bool              `yaml:"rbac"`
+	Roles       map[string]string `yaml:"roles"`
+	DefaultRole string            `yaml:"default_role"`
+	Tokens      map[string]string `yaml:"tokens"`
+	Token       string            `yaml:"token"`
+	// identities allowed to forward identity of caller. eg. cn of http gateway
+	TrustedProxies []string `yaml:"trusted_proxies"`
+
+	// token of daemon generated on start. not configurable
+	daemonToken string
+}
+
+// get default config
+func NewDefaultConfig() *Config {
+	return &Config{
+		NetPort:           NET_PORT,
+		VmPort:            VM_PORT,
+		SshPort:           SSH_PORT,
+		HttpPort:          HTTP_PORT,
+		NetSocket:         NET_SOCKET,
+		VmSocket:          VM_SOCKET,
+		DbPath:            "local.db",
+		LogDir:            "log",
+		SshHostKey:        "ssh_host_key",
+		SshAuthorizedKeys: "authorized_keys",
+		VolumeDir:         "/var/local/libvirt/volume",
+		LibvirtUri:        "qemu:///system",
+		ImageMaker:        "external/image_maker",
+		Roles:             map[string]string{IdentityLocal: RoleAdmin.String()},
+	}
+}
+
+// load config from yaml file over default config and override it by env vars,
+// then by override if not nil. eg. flags of cli. CONFIG_PATH is loaded if path is empty and it exists
+func LoadConfig(path string, override func(config *Config)) (*Config, error) {
+	config := NewDefaultConfig()
+
+	if path == "" && IsExist(CONFIG_PATH) {
+		path = CONFIG_PATH
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// roles of file replace default roles instead of being merged into them
+		defaultRoles := config.Roles
+		config.Roles = nil
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		if config.Roles == nil {
+			config.Roles = defaultRoles
+		}
+	}
+
+	if err := config.overrideByEnv(); err != nil {
+		return nil, err
+	}
+
+	if override != nil {
+		override(config)
+	}
+
+	// plain tcp listens on localhost only
+	if config.Tcp && !config.Tls && config.Host == "" {
+		config.Host = "localhost"
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// override fields by env vars of yaml name. eg. GOCLI_LIBVIRT_URI
+func (config *Config) overrideByEnv() error {
+	value := reflect.ValueOf(config).Elem()
+	for _, field := range reflect.VisibleFields(value.Type()) {
+		if !field.IsExported() {
+			continue
+		}
+
+		name := configEnvPrefix + strings.ToUpper(field.Tag.Get("yaml"))
+		env, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			value.FieldByIndex(field.Index).SetString(env)
+		case reflect.Int:
+			number, err := strconv.Atoi(env)
+			if err != nil {
+				return fmt.Errorf("%s: not a number \"%s\"", name, env)
+			}
+			value.FieldByIndex(field.Index).SetInt(int64(number))
+		case reflect.Bool:
+			boolean, err := strconv.ParseBool(env)
+			if err != nil {
+				return fmt.Errorf("%s: not a boolean \"%s\"", name, env)
+			}
+			value.FieldByIndex(field.Index).SetBool(boolean)
+		case reflect.Slice:
+			// comma separated list. eg. GOCLI_TRUSTED_PROXIES=10.0.0.1,10.0.0.2
+			list := make([]string, 0)
+			for _, item := range strings.Split(env, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			value.FieldByIndex(field.Index).Set(reflect.ValueOf(list))
+		case reflect.Map:
+			// comma separated key=value pairs. eg. GOCLI_ROLES=local=admin,alice=viewer
+			pairs := make(map[string]string)
+			for _, item := range strings.Split(env, ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				key, val, found := strings.Cut(item, "=")
+				if !found || strings.TrimSpace(key) == "" {
+					return fmt.Errorf("%s: not a key=value pair \"%s\"", name, item)
+				}
+				pairs[strings.TrimSpace(key)] = strings.TrimSpace(val)
+			}
+			value.FieldByIndex(field.Index).Set(reflect.ValueOf(pairs))
+		default:
+			return fmt.Errorf("%s: type %s is not supported", name, field.Type)
+		}
+	}
+
+	return nil
+}
+
+// validate config
+func (config *Config) Validate() error {
+	ports := map[string]int{"net_port": config.NetPort, "vm_port": config.VmPort, "ssh_port": config.SshPort,
+		"http_port": config.HttpPort}
+	used := make(map[int]string)
+	for _, name := range []string{"net_port", "vm_port", "ssh_port", "http_port"} {
+		port := ports[name]
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("%s: %d is out of range 1-65535", name, port)
+		}
+		if other, ok := used[port]; ok {
+			return fmt.Errorf("%s: %d is already used by %s", name, port, other)
+		}
+		used[port] = name
+	}
+
+	for name, path := range map[string]string{
+		"net_socket": config.NetSocket, "vm_socket": config.VmSocket, "db_path": config.DbPath,
+		"log_dir": config.LogDir, "ssh_host_key": config.SshHostKey, "volume_dir": config.VolumeDir,
+		"image_maker": config.ImageMaker,
+	} {
+		if path == "" {
+			return fmt.Errorf("%s: empty path", name)
+		}
+	}
+
+	if config.NetSocket == config.VmSocket {
+		return fmt.Errorf("vm_socket: %s is already used by net_socket", config.VmSocket)
+	}
+
+	if uri, err := url.Parse(config.LibvirtUri); err != nil || uri.Scheme == "" {
+		return fmt.Errorf("libvirt_uri: invalid uri \"%s\"", config.LibvirtUri)
+	}
+
+	if err := config.validateTls(); err != nil {
+		return err
+	}
+
+	return config.validateRbac()
+}
+
+// validate tls config. ca and client certificate are loaded if set,
+// and plain tcp is allowed on loopback host only. certificate of daemon
+// is loaded by daemon only, whose key is not readable by users of cli
+func (config *Config) validateTls() error {
+	if !config.Tls {
+		if config.Tcp && !isLoopbackHost(config.Host) {
+			return fmt.Errorf("host: %s is not loopback, tls is required", config.Host)
+		}
+		return nil
+	}
+
+	if (config.TlsCert == "") != (config.TlsKey == "") {
+		return fmt.Errorf("tls_cert, tls_key: both or neither are required")
+	}
+
+	if config.TlsCa != "" {
+		if _, err := loadCertPool(config.TlsCa); err != nil {
+			return fmt.Errorf("tls_ca: %v", err)
+		}
+	}
+
+	if (config.TlsClientCert == "") != (config.TlsClientKey == "") {
+		return fmt.Errorf("tls_client_cert, tls_client_key: both or neither are required")
+	}
+	if config.TlsClientCert != "" {
+		if _, err := loadKeyPair(config.TlsClientCert, config.TlsClientKey); err != nil {
+			return fmt.Errorf("tls_client_cert, tls_client_key: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// get path of log file in log dir. log dir is created if not exist
+func (config *Config) GetLogPath(name string) string {
+	if _, err := os.Stat(config.LogDir); os.IsNotExist(err) {
+		os.MkdirAll(config.LogDir, 0700)
+	}
+
+	return filepath.Join(config.LogDir, name)
+}
+
+// get network and address of networker
+func (config *Config) GetNetAddress() (string, string) {
+	return GetServiceAddress(config.Tcp, config.Host, config.NetPort, config.NetSocket)
+}
+
+// get network and address of vmer
+func (config *Config) GetVmAddress() (string, string) {
+	return GetServiceAddress(config.Tcp, config.Host, config.VmPort, config.VmSocket)
+}
+
+// get address of http gateway. plain http listens on loopback host only
+func (config *Config) GetHttpAddress() (string, error) {
+	host := config.Host
+	if host == "" && !config.Tls {
+		host = "localhost"
+	}
+
+	if !config.Tls && !isLoopbackHost(host) {
+		return "", fmt.Errorf("host: %s is not loopback, tls is required", host)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(config.HttpPort)), nil
+}
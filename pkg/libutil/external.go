@@ -5,8 +5,8 @@ import (
 	"os/exec"
 )
 
-// run external image maker
-func RunExternalImageMaker(path string, username string, publicKey string, mac string, vlan int32,
+// run external image maker of image maker path
+func RunExternalImageMaker(imageMaker string, path string, username string, publicKey string, mac string, vlan int32,
 	ip string, gateway string, dns string) (string, error) {
 
 	type JsonData struct {
@@ -38,7 +38,7 @@ func RunExternalImageMaker(path string, username string, publicKey string, mac s
 		return "", err
 	}
 
-	cmd := exec.Command("external/image_maker", string(jsonData))
+	cmd := exec.Command(imageMaker, string(jsonData))
 	out, err := cmd.Output()
 	if err != nil {
 		return string(out), err
@@ -0,0 +1,221 @@
+package libutil
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table"
+	OutputWide  = "wide"
+	OutputJson  = "json"
+	OutputYaml  = "yaml"
+	OutputCsv   = "csv"
+)
+
+// check output format is supported
+func IsOutputFormat(format string) bool {
+	switch format {
+	case OutputTable, OutputWide, OutputJson, OutputYaml, OutputCsv:
+		return true
+	default:
+		return false
+	}
+}
+
+// struct field with name and converted value
+type field struct {
+	name  string
+	value any
+}
+
+// fields of struct keeping struct field order
+type fields []field
+
+// marshal fields as json object in struct field order
+func (fs fields) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, f := range fs {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		key, err := json.Marshal(fieldKey(f.name))
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+
+	return buf.Bytes(), nil
+}
+
+// lower first letter of field name for json and yaml key
+func fieldKey(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// convert value to fields, slice or scalar keeping struct field order
+func convertValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface || v.Elem().Kind() == reflect.Struct {
+			return convertValue(v.Elem())
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fs := make(fields, 0)
+		for _, name := range GetFieldNameList(v.Type()) {
+			fs = append(fs, field{name: name, value: convertValue(v.FieldByName(name))})
+		}
+		return fs
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("%s", v.Interface())
+		}
+		values := make([]any, 0)
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, convertValue(v.Index(i)))
+		}
+		return values
+	}
+
+	// enum and other named types print as string
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	return v.Interface()
+}
+
+func writeJson(w io.Writer, slice []any) error {
+	data, err := json.MarshalIndent(convertValue(reflect.ValueOf(slice)), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	_, err = w.Write(data)
+	return err
+}
+
+// convert value to yaml node
+func yamlNode(value any) (*yaml.Node, error) {
+	switch value := value.(type) {
+	case fields:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, f := range value {
+			valueNode, err := yamlNode(f.value)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: fieldKey(f.name)}, valueNode)
+		}
+		return node, nil
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, each := range value {
+			valueNode, err := yamlNode(each)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, valueNode)
+		}
+		return node, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(value); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+func writeYaml(w io.Writer, slice []any) error {
+	node, err := yamlNode(convertValue(reflect.ValueOf(slice)))
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// convert value to csv cell. nested struct and slice are encoded as json
+func csvValue(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case fields, []any:
+		data, err := json.Marshal(value)
+		return string(data), err
+	default:
+		return fmt.Sprintf("%v", value), nil
+	}
+}
+
+func writeCsv(w io.Writer, slice []any) error {
+	if len(slice) == 0 {
+		return nil
+	}
+
+	fieldNameList := GetFieldNameList(reflect.TypeOf(slice[0]))
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fieldNameList); err != nil {
+		return err
+	}
+
+	for _, elem := range slice {
+		e := reflect.ValueOf(elem)
+		if e.Kind() == reflect.Ptr {
+			e = e.Elem()
+		}
+
+		record := make([]string, 0)
+		for _, name := range fieldNameList {
+			value, err := csvValue(convertValue(e.FieldByName(name)))
+			if err != nil {
+				return err
+			}
+			record = append(record, value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
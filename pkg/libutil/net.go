@@ -4,8 +4,12 @@ import (
 	"crypto/rand"
 	"fmt"
 	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+
+	"golang.org/x/sys/unix"
 )
 
 // generate mac address
@@ -52,3 +56,84 @@ func GenerateMacAddressByIp(ipWithMask string) (string, error) {
 	return fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x",
 		hexMacSlice[0], hexMacSlice[1], hexMacSlice[2], hexMacSlice[3], hexMacSlice[4], hexMacSlice[5]), nil
 }
+
+// get network and address of service. unix socket unless tcp
+func GetServiceAddress(isTcp bool, host string, port int, socketPath string) (string, string) {
+	if isTcp {
+		return "tcp", fmt.Sprintf("%s:%d", host, port)
+	}
+
+	return "unix", socketPath
+}
+
+// get grpc dial target of network and address
+func GetDialTarget(network string, address string) string {
+	if network == "unix" {
+		return "unix:" + address
+	}
+
+	return address
+}
+
+// address of unix socket peer with credentials of SO_PEERCRED
+type unixPeerAddr struct {
+	net.Addr
+	cred *unix.Ucred
+}
+
+// unix socket conn of peer with credentials
+type unixPeerConn struct {
+	net.Conn
+	addr *unixPeerAddr
+}
+
+func (conn *unixPeerConn) RemoteAddr() net.Addr {
+	return conn.addr
+}
+
+// unix socket listener getting credentials of each peer
+type unixPeerListener struct {
+	net.Listener
+}
+
+// accept conn of peer. credentials are nil if SO_PEERCRED fails
+func (listener *unixPeerListener) Accept() (net.Conn, error) {
+	conn, err := listener.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	addr := &unixPeerAddr{Addr: conn.RemoteAddr()}
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		if rawConn, err := unixConn.SyscallConn(); err == nil {
+			rawConn.Control(func(fd uintptr) {
+				addr.cred, _ = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+			})
+		}
+	}
+
+	return &unixPeerConn{Conn: conn, addr: addr}, nil
+}
+
+// listen on network and address. stale unix socket of killed daemon is removed,
+// and credentials of unix socket peers are got for their identity
+func Listen(network string, address string) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(address), 0700); err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unixPeerListener{listener}, nil
+}
@@ -1,24 +1,82 @@
 package libutil
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+)
 
 func ClearLine() {
-	fmt.Printf("\033[2K\r")
+	FprintClearLine(os.Stdout)
+}
+
+// clear line of terminal writer
+func FprintClearLine(w io.Writer) {
+	fmt.Fprintf(w, "\033[2K\r")
 }
 
 func ClearScreen() {
-	fmt.Printf("\033[2J")
+	FprintClearScreen(os.Stdout)
+}
+
+// clear screen of terminal writer
+func FprintClearScreen(w io.Writer) {
+	fmt.Fprintf(w, "\033[2J\033[H")
 }
 
 // move cursor
 func MoveCursor(direction int) {
+	FprintMoveCursor(os.Stdout, direction)
+}
+
+// move cursor of terminal writer
+func FprintMoveCursor(w io.Writer, direction int) {
 	if direction == 0 {
 		return
 	}
 
 	if direction > 0 {
-		fmt.Printf("\033[%dC", direction)
+		fmt.Fprintf(w, "\033[%dC", direction)
 	} else {
-		fmt.Printf("\033[%dD", -direction)
+		fmt.Fprintf(w, "\033[%dD", -direction)
+	}
+}
+
+// get display width of rune. east asian wide runes take two columns
+func RuneWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r >= 0x1100 && r <= 0x115f, // hangul jamo
+		r >= 0x2e80 && r <= 0x303e, // cjk radicals, punctuation
+		r >= 0x3041 && r <= 0x33ff, // kana, cjk symbols
+		r >= 0x3400 && r <= 0x4dbf, // cjk extension a
+		r >= 0x4e00 && r <= 0x9fff, // cjk unified ideographs
+		r >= 0xa960 && r <= 0xa97f, // hangul jamo extended-a
+		r >= 0xac00 && r <= 0xd7a3, // hangul syllables
+		r >= 0xf900 && r <= 0xfaff, // cjk compatibility ideographs
+		r >= 0xfe30 && r <= 0xfe4f, // cjk compatibility forms
+		r >= 0xff00 && r <= 0xff60, // fullwidth forms
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd: // cjk extension b and beyond
+		return 2
+	default:
+		return 1
 	}
 }
+
+// get display width of runes
+func RunesWidth(runes []rune) int {
+	width := 0
+	for _, r := range runes {
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// highlight string by reverse video
+func Highlight(str string) string {
+	return fmt.Sprintf("\033[7m%s\033[0m", str)
+}
@@ -0,0 +1,337 @@
+package libutil
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	nblogger "github.com/banaconda/nb-logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// identity of unix socket or plain loopback peer
+const IdentityLocal = "local"
+
+// metadata key of token
+const tokenMetadataKey = "authorization"
+const tokenPrefix = "Bearer "
+
+// metadata key of identity forwarded by proxy. eg. http gateway
+const ForwardedIdentityKey = "x-forwarded-identity"
+
+// identity of token of daemon, which is trusted to forward identity of callers.
+// eg. http gateway and clis of ssh sessions of daemon
+const identityDaemon = "daemon"
+
+// role of identity. higher role has permissions of lower roles
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleOperator
+	RoleAdmin
+)
+
+var roleNames = []string{"none", "viewer", "operator", "admin"}
+
+func (role Role) String() string {
+	if role < RoleNone || role > RoleAdmin {
+		return "unknown"
+	}
+	return roleNames[role]
+}
+
+// parse role name. eg. viewer
+func ParseRole(name string) (Role, error) {
+	for i, roleName := range roleNames {
+		if roleName == name {
+			return Role(i), nil
+		}
+	}
+
+	return RoleNone, fmt.Errorf("unknown role \"%s\"", name)
+}
+
+// validate rbac config
+func (config *Config) validateRbac() error {
+	for identity, name := range config.Roles {
+		if _, err := ParseRole(name); err != nil {
+			return fmt.Errorf("roles: %s: %v", identity, err)
+		}
+	}
+
+	if config.DefaultRole != "" {
+		if _, err := ParseRole(config.DefaultRole); err != nil {
+			return fmt.Errorf("default_role: %v", err)
+		}
+	}
+
+	for token, identity := range config.Tokens {
+		if token == "" || identity == "" {
+			return fmt.Errorf("tokens: empty token or identity")
+		}
+	}
+
+	// token is sent in plain text without tls
+	if config.Token != "" && !config.Tls {
+		return fmt.Errorf("token: tls is required")
+	}
+
+	return nil
+}
+
+// get role of identity. default role is used if identity has no role
+func (config *Config) getRole(identity string) Role {
+	name, ok := config.Roles[identity]
+	if !ok {
+		name = config.DefaultRole
+	}
+
+	role, err := ParseRole(name)
+	if err != nil {
+		return RoleNone
+	}
+
+	return role
+}
+
+// get identity of grpc caller. identity of token is used if token is sent,
+// and identity forwarded by local peer or trusted proxy is used if forwarded
+func (config *Config) getIdentity(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	identity := GetPeerIdentity(ctx)
+	if values := md.Get(tokenMetadataKey); len(values) > 0 {
+		token := strings.TrimPrefix(values[0], tokenPrefix)
+		if config.isDaemonToken(token) {
+			identity = identityDaemon
+		} else if tokenIdentity, ok := config.Tokens[token]; ok {
+			identity = tokenIdentity
+		} else {
+			return "", errors.New("invalid token")
+		}
+	}
+
+	if values := md.Get(ForwardedIdentityKey); len(values) > 0 {
+		isTrusted := identity == IdentityLocal || identity == identityDaemon ||
+			Contains(config.TrustedProxies, identity)
+		if !isTrusted {
+			return "", fmt.Errorf("%s is not a trusted proxy", identity)
+		}
+		// local and daemon are not forwarded, so they are granted to local peers only
+		if values[0] == IdentityLocal || values[0] == identityDaemon {
+			return "", fmt.Errorf("identity %s is not forwarded", values[0])
+		}
+		return values[0], nil
+	}
+
+	return identity, nil
+}
+
+// generate token of daemon. in-process clients of daemon send it to be trusted
+// to forward identity of callers, even over loopback tcp
+func (config *Config) InitDaemonToken() error {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+
+	config.daemonToken = hex.EncodeToString(token)
+	return nil
+}
+
+// is token of daemon
+func (config *Config) isDaemonToken(token string) bool {
+	return config.daemonToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(config.daemonToken)) == 1
+}
+
+type identityKey struct{}
+
+// get identity of grpc caller authorized by interceptor
+func GetIdentity(ctx context.Context) string {
+	if identity, ok := ctx.Value(identityKey{}).(string); ok {
+		return identity
+	}
+
+	return GetPeerIdentity(ctx)
+}
+
+// server stream with context of identity
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *identityStream) Context() context.Context {
+	return stream.ctx
+}
+
+// get grpc server options authorizing each call by role of identity.
+// permissions map method name to required role. admin is required if not in permissions
+func (config *Config) GetAuthorizeOptions(logger nblogger.Logger, permissions map[string]Role) []grpc.ServerOption {
+	authorize := func(ctx context.Context, fullMethod string) (context.Context, error) {
+		identity, err := config.getIdentity(ctx)
+		if err != nil {
+			logger.Warn("%s: %s: %v", GetPeerIdentity(ctx), fullMethod, err)
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		ctx = context.WithValue(ctx, identityKey{}, identity)
+
+		if !config.Rbac {
+			logger.Info("%s: %s", identity, fullMethod)
+			return ctx, nil
+		}
+
+		method := path.Base(fullMethod)
+		required, ok := permissions[method]
+		if !ok {
+			required = RoleAdmin
+		}
+
+		role := config.getRole(identity)
+		if role < required {
+			logger.Warn("%s(%s): %s denied", identity, role, fullMethod)
+			return nil, status.Errorf(codes.PermissionDenied, "%s requires %s role, %s has %s role",
+				method, required, identity, role)
+		}
+
+		logger.Info("%s(%s): %s", identity, role, fullMethod)
+		return ctx, nil
+	}
+
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{ss, ctx})
+	}
+
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream)}
+}
+
+// per rpc credentials of token
+type tokenCredentials string
+
+func (token tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{tokenMetadataKey: tokenPrefix + string(token)}, nil
+}
+
+func (token tokenCredentials) RequireTransportSecurity() bool {
+	return true
+}
+
+// client conn forwarding identity of caller. eg. user of ssh session
+type forwardingConn struct {
+	grpc.ClientConnInterface
+	config   *Config
+	identity string
+}
+
+func (conn *forwardingConn) Invoke(ctx context.Context, method string, args, reply interface{},
+	opts ...grpc.CallOption) error {
+	ctx = conn.config.AppendForwardedIdentity(ctx, conn.identity)
+	return conn.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
+}
+
+func (conn *forwardingConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string,
+	opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	ctx = conn.config.AppendForwardedIdentity(ctx, conn.identity)
+	return conn.ClientConnInterface.NewStream(ctx, desc, method, opts...)
+}
+
+// get client conn forwarding identity on each call. conn is returned as is if identity is empty
+func (config *Config) ForwardIdentity(conn grpc.ClientConnInterface, identity string) grpc.ClientConnInterface {
+	if identity == "" {
+		return conn
+	}
+
+	return &forwardingConn{ClientConnInterface: conn, config: config, identity: identity}
+}
+
+// append identity of caller to outgoing context. token of daemon is appended if generated,
+// since forwarding is allowed to trusted proxies only
+func (config *Config) AppendForwardedIdentity(ctx context.Context, identity string) context.Context {
+	ctx = metadata.AppendToOutgoingContext(ctx, ForwardedIdentityKey, identity)
+	if config.daemonToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, tokenMetadataKey, tokenPrefix+config.daemonToken)
+	}
+
+	return ctx
+}
+
+// format error of authorization for cli. other errors are returned as is
+func formatRpcError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.PermissionDenied:
+		return fmt.Errorf("permission denied: %s", st.Message())
+	case codes.Unauthenticated:
+		return fmt.Errorf("unauthenticated: %s", st.Message())
+	}
+
+	return err
+}
+
+// client stream formatting error of authorization
+type formatErrorStream struct {
+	grpc.ClientStream
+}
+
+func (stream *formatErrorStream) SendMsg(m interface{}) error {
+	return formatRpcError(stream.ClientStream.SendMsg(m))
+}
+
+func (stream *formatErrorStream) RecvMsg(m interface{}) error {
+	return formatRpcError(stream.ClientStream.RecvMsg(m))
+}
+
+// get grpc dial options of tls and token. errors of authorization are formatted for cli
+func (config *Config) GetDialOptions() ([]grpc.DialOption, error) {
+	transport, err := config.GetDialOption()
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{transport}
+
+	// clis of daemon send token of daemon instead
+	if config.Token != "" && config.daemonToken == "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(config.Token)))
+	}
+
+	unary := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return formatRpcError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			return nil, formatRpcError(err)
+		}
+		return &formatErrorStream{cs}, nil
+	}
+
+	return append(opts, grpc.WithChainUnaryInterceptor(unary), grpc.WithChainStreamInterceptor(stream)), nil
+}
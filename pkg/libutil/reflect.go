@@ -2,6 +2,8 @@ package libutil
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"reflect"
 )
 
@@ -28,8 +30,35 @@ func PrintStructOne(elem any) {
 }
 
 func PrintStructAll(elems interface{}) {
+	FprintStructAll(os.Stdout, OutputTable, elems)
+}
+
+// print struct slice to writer by output format
+func FprintStructAll(w io.Writer, format string, elems interface{}) error {
 	slice, ok := CreateAnyTypeSlice(elems)
-	if !ok || len(slice) == 0 {
+	if !ok {
+		return fmt.Errorf("%T is not slice", elems)
+	}
+
+	switch format {
+	case OutputJson:
+		return writeJson(w, slice)
+	case OutputYaml:
+		return writeYaml(w, slice)
+	case OutputCsv:
+		return writeCsv(w, slice)
+	case OutputWide:
+		writeTable(w, slice, false)
+	default:
+		writeTable(w, slice, true)
+	}
+
+	return nil
+}
+
+// write struct slice as table. if truncate is true, long values are truncated
+func writeTable(w io.Writer, slice []any, truncate bool) {
+	if len(slice) == 0 {
 		return
 	}
 
@@ -58,7 +87,7 @@ func PrintStructAll(elems interface{}) {
 			valueString := fmt.Sprintf("%v", value)
 
 			// if valueString is longer than 20 characters, truncate it and add ...
-			if len(valueString) > 20 {
+			if truncate && len(valueString) > 20 {
 				valueString = valueString[:20] + "..."
 			}
 
@@ -72,9 +101,9 @@ func PrintStructAll(elems interface{}) {
 
 	for _, line := range lines {
 		for i, value := range line {
-			fmt.Printf("%*s", maxWidths[i]+1, value)
+			fmt.Fprintf(w, "%*s", maxWidths[i]+1, value)
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 }
 
@@ -10,3 +10,13 @@ func RemoveEmptyString(strSlice []string) []string {
 	}
 	return ret
 }
+
+// check string slice contains string
+func Contains(strSlice []string, str string) bool {
+	for _, s := range strSlice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package libutil
+
+import (
+	"context"
+	"errors"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// get grpc code of errno. eg. EEXIST of netlink. unknown if error is not errno
+func getErrnoCode(err error) codes.Code {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return codes.Unknown
+	}
+
+	switch errno {
+	case syscall.EEXIST:
+		return codes.AlreadyExists
+	case syscall.ENOENT, syscall.ESRCH, syscall.ENODEV, syscall.ENXIO:
+		return codes.NotFound
+	case syscall.EINVAL, syscall.ERANGE, syscall.EAFNOSUPPORT:
+		return codes.InvalidArgument
+	default:
+		return codes.Unknown
+	}
+}
+
+// convert error to status of code by getCode, or by errno if code is unknown.
+// status error is returned as is
+func ToStatusError(err error, getCode func(err error) codes.Code) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code := codes.Unknown
+	if getCode != nil {
+		code = getCode(err)
+	}
+	if code == codes.Unknown {
+		code = getErrnoCode(err)
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// get grpc server options converting errors of handlers to status. eg. not found, already exists
+func GetStatusOptions(getCode func(err error) codes.Code) []grpc.ServerOption {
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, ToStatusError(err, getCode)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		return ToStatusError(handler(srv, ss), getCode)
+	}
+
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary), grpc.ChainStreamInterceptor(stream)}
+}
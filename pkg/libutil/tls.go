@@ -0,0 +1,175 @@
+package libutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+)
+
+// load cert pool of ca file
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s: no certificate", path)
+	}
+
+	return pool, nil
+}
+
+// load certificate and key. certificate must be valid now
+func loadKeyPair(certPath string, keyPath string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return cert, err
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return cert, err
+	}
+	if now := time.Now(); now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return cert, fmt.Errorf("%s: certificate is valid from %s to %s", certPath,
+			leaf.NotBefore.Format(time.RFC3339), leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	return cert, nil
+}
+
+// is host of loopback address. eg. localhost, 127.0.0.1, ::1
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// get tls config of server. client certificate is verified by ca if set
+func (config *Config) GetServerTlsConfig() (*tls.Config, error) {
+	if config.TlsCert == "" || config.TlsKey == "" {
+		return nil, fmt.Errorf("tls_cert, tls_key: required by tls server")
+	}
+
+	cert, err := loadKeyPair(config.TlsCert, config.TlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("tls_cert, tls_key: %v", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if config.TlsCa != "" {
+		if tlsConfig.ClientCAs, err = loadCertPool(config.TlsCa); err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// get grpc server options of tls. client certificate is verified by ca if set,
+// and required unless tokens are set
+func (config *Config) GetServerOptions() ([]grpc.ServerOption, error) {
+	if !config.Tls {
+		return nil, nil
+	}
+
+	tlsConfig, err := config.GetServerTlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// mutual tls. clients of token may not have certificate
+	if config.TlsCa != "" && len(config.Tokens) == 0 {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(tlsConfig))}, nil
+}
+
+// get grpc dial option of tls. server is verified by ca or system cert pool
+func (config *Config) GetDialOption() (grpc.DialOption, error) {
+	if !config.Tls {
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: config.TlsServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.TlsCa != "" {
+		var err error
+		if tlsConfig.RootCAs, err = loadCertPool(config.TlsCa); err != nil {
+			return nil, err
+		}
+	}
+
+	// mutual tls
+	if config.TlsClientCert != "" {
+		cert, err := loadKeyPair(config.TlsClientCert, config.TlsClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
+
+// get identity of grpc peer. common name of verified client certificate,
+// local for unix socket peer of root or user of daemon, unix:user for other unix socket peers,
+// or address of peer without client certificate
+func GetPeerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		chains := tlsInfo.State.VerifiedChains
+		if len(chains) > 0 && len(chains[0]) > 0 {
+			return chains[0][0].Subject.CommonName
+		}
+	}
+
+	// loopback tcp peers are not local, since any user of host may connect
+	if addr, ok := p.Addr.(*unixPeerAddr); ok && addr.cred != nil {
+		if addr.cred.Uid == 0 || int(addr.cred.Uid) == os.Getuid() {
+			return IdentityLocal
+		}
+		return "unix:" + uidToUserName(addr.cred.Uid)
+	}
+
+	return p.Addr.String()
+}
+
+// get user name of uid. uid if user does not exist
+func uidToUserName(uid uint32) string {
+	uidString := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(uidString); err == nil {
+		return u.Username
+	}
+
+	return uidString
+}
@@ -0,0 +1,104 @@
+package libvm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-cli/pkg/libcli"
+	"go-cli/pkg/libutil"
+	"go-cli/pkg/libvm/vmer"
+	"time"
+)
+
+// get audit messages of cli params since and resource
+func getAudits(client vmer.VmerClient, params map[string]string) ([]*vmer.AuditMessage, error) {
+	query := &vmer.AuditQuery{Resource: params["resource"]}
+	if params["since"] != "" {
+		since, err := libutil.ParseSince(params["since"], time.Now())
+		if err != nil {
+			return nil, err
+		}
+		query.Since = since.Unix()
+	}
+
+	// no deadline, since audit logs may be too many to be streamed in seconds
+	stream, err := client.ShowAudits(context.Background(), query)
+	if err != nil {
+		cliLogger.Warn("%v", err)
+		return nil, err
+	}
+
+	var streamInterface StreamInterface[*vmer.AuditMessage] = stream
+
+	return recvStream(streamInterface)
+}
+
+func initAuditCli(cli *libcli.GoCli, client vmer.VmerClient) {
+	// show audit logs
+	cli.AddCommandElem(
+		nce("audit", "audit log of mutating operations"),
+		ncep("show", "show audit logs", []*libcli.Param{
+			np("since", libcli.KindTime, "since duration ago or time"),
+			np("resource", libcli.KindName, "resource name. eg. route, domain"),
+		}, func(params map[string]string) error {
+			messages, err := getAudits(client, params)
+			if err != nil {
+				return err
+			}
+
+			cli.PrintStructAll(messages)
+			return nil
+		}))
+
+	// export audit logs as json to terminal of cli, since cli of ssh session runs on daemon host.
+	// eg. -c "audit export since 24h" > audit.json
+	cli.AddCommandElem(
+		nce("audit", "audit log of mutating operations"),
+		ncep("export", "export audit logs as json to terminal", []*libcli.Param{
+			np("since", libcli.KindTime, "since duration ago or time"),
+			np("resource", libcli.KindName, "resource name. eg. route, domain"),
+		}, func(params map[string]string) error {
+			messages, err := getAudits(client, params)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(messages, "", "  ")
+			if err != nil {
+				cliLogger.Warn("%v", err)
+				return err
+			}
+
+			cli.Printf("%s\n", data)
+			return nil
+		}))
+}
+
+// show audit logs of networker and vmer
+func (s *server) ShowAudits(in *vmer.AuditQuery, stream vmer.Vmer_ShowAuditsServer) error {
+	if auditDB == nil {
+		return fmt.Errorf("audit db is not opened")
+	}
+
+	auditLogs, err := auditDB.Find(time.Unix(in.Since, 0), in.Resource)
+	if err != nil {
+		logger.Warn("failed to get audit logs: %v", err)
+		return err
+	}
+
+	for _, auditLog := range auditLogs {
+		if err := stream.Send(&vmer.AuditMessage{
+			Time:     auditLog.CreatedAt.Format(time.RFC3339),
+			Identity: auditLog.Identity,
+			Service:  auditLog.Service,
+			Method:   auditLog.Method,
+			Resource: auditLog.Resource,
+			Request:  auditLog.Request,
+			Result:   auditLog.Result,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,111 +2,110 @@ package libvm
 
 import (
 	"context"
-	"fmt"
 	"go-cli/pkg/libcli"
 	"go-cli/pkg/libutil"
 	"go-cli/pkg/libvm/vmer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"os"
 	"time"
 )
 
-func initBaseImageCli(cli *libcli.GoCli) {
-
-	// show base image
+func initBaseImageCli(cli *libcli.GoCli, client vmer.VmerClient) {
+	// show base images. base image of name if name is set
 	cli.AddCommandElem(
 		nce("base-image", "base image"),
-		ncef("show", "show base image", func(args []string) {
-			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
-			defer cancel()
-			stream, err := client.ShowBaseImages(ctx, &vmer.BaseImageMessage{})
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			// stream to StreamInterface
-			var streamInterface StreamInterface[*vmer.BaseImageMessage] = stream
-
-			messages, err := recvStream(streamInterface)
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			libutil.PrintStructAll(messages)
-		}))
-
-	// show base image by name
-	cli.AddCommandElem(
-		nce("base-image", "base image"),
-		nce("show", "show base image"),
-		nce("name", "base image name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
+		ncep("show", "show base image", []*libcli.Param{
+			np("name", libcli.KindName, "base image name").SetCompleter(completeBaseImage(client)),
+		}, func(params map[string]string) error {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
 			stream, err := client.ShowBaseImages(ctx, &vmer.BaseImageMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+
+			// stream to StreamInterface
 			var streamInterface StreamInterface[*vmer.BaseImageMessage] = stream
 
 			messages, err := recvStream(streamInterface)
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
-			libutil.PrintStructAll(messages)
+			cli.PrintStructAll(messages)
+			return nil
 		}))
 
-	// upload base image
+	// upload base image by name path in any order
 	cli.AddCommandElem(
 		nce("base-image", "base image"),
-		nce("upload", "upload base image"),
-		nce("name", "base image name"),
-		nce(libutil.NameRegex, ""),
-		nce("path", "base image path"),
-		ncef(libutil.FilePathRegex, "", func(args []string) {
+		ncep("upload", "upload base image", []*libcli.Param{
+			np("name", libcli.KindName, "base image name").SetRequired(),
+			np("path", libcli.KindExistPath, "base image path").SetRequired(),
+		}, func(params map[string]string) error {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
 
 			r, err := client.UploadBaseImage(ctx, &vmer.BaseImageMessage{
-				Name: args[3],
-				Path: args[5],
+				Name: params["name"],
+				Path: params["path"],
 			})
 
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
-			libutil.PrintStructAll(r)
+			cli.PrintStructOne(r)
+			return nil
 		}))
 
 	// delete base image
 	cli.AddCommandElem(
 		nce("base-image", "base image"),
-		nce("delete", "delete base image"),
-		nce("name", "base image name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
+		ncep("delete", "delete base image", []*libcli.Param{
+			np("name", libcli.KindName, "base image name").SetRequired().SetCompleter(completeBaseImage(client)),
+		}, func(params map[string]string) error {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 			defer cancel()
 
 			r, err := client.DeleteBaseImage(ctx, &vmer.BaseImageMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
-			libutil.PrintStructAll(r)
+			cli.PrintStructOne(r)
+			return nil
 		}))
+}
 
+// complete base image names
+func completeBaseImage(client vmer.VmerClient) func(args []string) []string {
+	return func(args []string) []string {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		stream, err := client.ShowBaseImages(ctx, &vmer.BaseImageMessage{})
+		if err != nil {
+			return nil
+		}
+
+		var streamInterface StreamInterface[*vmer.BaseImageMessage] = stream
+		messages, err := recvStream(streamInterface)
+		if err != nil {
+			return nil
+		}
+
+		return getNames(messages)
+	}
 }
 
 // show base images
@@ -147,7 +146,7 @@ func (s *server) ShowBaseImages(in *vmer.BaseImageMessage, stream vmer.Vmer_Show
 func (s *server) UploadBaseImage(ctx context.Context, in *vmer.BaseImageMessage) (*vmer.BaseImageMessage, error) {
 	// check file is exist
 	if !libutil.IsExist(in.Path) {
-		return nil, fmt.Errorf("file %s is not exist", in.Path)
+		return nil, status.Errorf(codes.NotFound, "file %s is not exist", in.Path)
 	}
 
 	// path is qcow2
@@ -159,7 +158,7 @@ func (s *server) UploadBaseImage(ctx context.Context, in *vmer.BaseImageMessage)
 
 	if !libutil.IsQcow2(file) {
 		logger.Warn("path is not qcow2")
-		return nil, fmt.Errorf("path is not qcow2")
+		return nil, status.Error(codes.InvalidArgument, "path is not qcow2")
 	}
 
 	size, err := libutil.GetFileSize(file)
@@ -6,17 +6,26 @@ import (
 	"go-cli/pkg/libutil"
 	"go-cli/pkg/libvm/vmer"
 	"io"
-	"log"
+	"sync"
 
+	nblogger "github.com/banaconda/nb-logger"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
+// connection is shared by clis of ssh sessions
 var conn *grpc.ClientConn
-var client vmer.VmerClient
+var connErr error
+var connOnce sync.Once
+
+// logger of clients. logger of server is not set when cli runs without daemon
+var cliLogger nblogger.Logger
 
 var nce = libcli.NewCommandElemWithoutFunc
 var ncef = libcli.NewCommandElem
+var nca = libcli.NewArgElemWithoutFunc
+var ncaf = libcli.NewArgElem
+var ncep = libcli.NewCommandElemWithParams
+var np = libcli.NewParam
 
 type StreamInterface[V ValueType] interface {
 	Recv() (V, error)
@@ -24,7 +33,8 @@ type StreamInterface[V ValueType] interface {
 }
 
 type ValueType interface {
-	*vmer.BaseImageMessage | *vmer.KeyMessage | *vmer.NetworkMessage | *vmer.VolumeMessage | *vmer.DomainMessage
+	*vmer.BaseImageMessage | *vmer.KeyMessage | *vmer.NetworkMessage | *vmer.VolumeMessage | *vmer.DomainMessage |
+		*vmer.AuditMessage
 }
 
 func recvStream[V ValueType](stream StreamInterface[V]) ([]V, error) {
@@ -36,7 +46,7 @@ func recvStream[V ValueType](stream StreamInterface[V]) ([]V, error) {
 		}
 
 		if err != nil {
-			logger.Warn("%v", err)
+			cliLogger.Warn("%v", err)
 			return nil, err
 		}
 		messages = append(messages, msg)
@@ -45,18 +55,45 @@ func recvStream[V ValueType](stream StreamInterface[V]) ([]V, error) {
 	return messages, nil
 }
 
-func InitCli(cli *libcli.GoCli) {
-	var err error
-	conn, err = grpc.Dial(fmt.Sprintf("%s:%d", "localhost", libutil.VM_PORT),
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Fatalf("did not connect: %v", err)
+type namedValueType interface {
+	ValueType
+	GetName() string
+}
+
+// get names of messages for tab completion
+func getNames[V namedValueType](messages []V) []string {
+	names := make([]string, 0)
+	for _, message := range messages {
+		names = append(names, message.GetName())
 	}
-	client = vmer.NewVmerClient(conn)
 
-	initBaseImageCli(cli)
-	initNetworkCli(cli)
-	initKeyCli(cli)
-	initVolumeCli(cli)
-	initDomainCli(cli)
+	return names
+}
+
+// init cli of vmer at grpc target. eg. unix:/run/go-cli/vm.sock
+func InitCli(cli *libcli.GoCli, config *libutil.Config, identity string, clientLogger nblogger.Logger) error {
+	connOnce.Do(func() {
+		cliLogger = clientLogger
+		dialOptions, err := config.GetDialOptions()
+		if err != nil {
+			connErr = fmt.Errorf("failed to load tls: %v", err)
+			return
+		}
+		conn, connErr = grpc.Dial(libutil.GetDialTarget(config.GetVmAddress()), dialOptions...)
+	})
+	if connErr != nil {
+		return connErr
+	}
+
+	// identity of caller is forwarded if set. eg. user of ssh session
+	client := vmer.NewVmerClient(config.ForwardIdentity(conn, identity))
+
+	initBaseImageCli(cli, client)
+	initNetworkCli(cli, client)
+	initKeyCli(cli, client)
+	initVolumeCli(cli, client)
+	initDomainCli(cli, client)
+	initAuditCli(cli, client)
+
+	return nil
 }
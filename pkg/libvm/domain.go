@@ -8,168 +8,196 @@ import (
 	"go-cli/pkg/libvm/vmer"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/libvirt/libvirt-go"
+	"google.golang.org/grpc"
 )
 
 // init domain cli
-func initDomainCli(cli *libcli.GoCli) {
+func initDomainCli(cli *libcli.GoCli, client vmer.VmerClient) {
 	// show domain
 	cli.AddCommandElem(
 		nce("domain", "domain"),
-		ncef("show", "show domains", func(args []string) {
+		ncef("show", "show domains", func(args []string) error {
 			stream, err := client.ShowDomains(context.Background(), &vmer.DomainMessage{})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
 			var streamInterface StreamInterface[*vmer.DomainMessage] = stream
 
 			messages, err := recvStream(streamInterface)
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
-			libutil.PrintStructAll(messages)
+			cli.PrintStructAll(messages)
+			return nil
 		}))
 
 	// show domain by name
 
-	// create domain by name cpu memory disksize mac ip key volume network
+	// create domain by name cpu memory disk-size mac ip key image network bridge in any order
 	cli.AddCommandElem(
 		nce("domain", "domain"),
-		nce("create", "create domain"),
-		nce("name", "domain name"),
-		nce(libutil.NameRegex, "domain name"),
-		nce("cpu", "cpu number"),
-		nce(libutil.NumberRegex, "cpu number"),
-		nce("memory", "memory size"),
-		nce(libutil.UnitRegex, "memory size"),
-		nce("disk-size", "disk size"),
-		nce(libutil.UnitRegex, "disk size"),
-		nce("mac", "mac address"),
-		nce(libutil.MacRegex, "mac address"),
-		nce("ip", "ip address with mask"),
-		nce(libutil.CidrRegex, "ip address"),
-		nce("key", "key name"),
-		nce(libutil.NameRegex, "key name"),
-		nce("image", "image name"),
-		nce(libutil.NameRegex, "base image name"),
-		nce("network", "network name"),
-		nce(libutil.NameRegex, ""),
-		nce("bridge", "bridge name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
-			vcpu, err := strconv.ParseInt(args[5], 10, 64)
+		ncep("create", "create domain", []*libcli.Param{
+			np("name", libcli.KindName, "domain name").SetRequired(),
+			np("cpu", libcli.KindInt(1, 256), "cpu number").SetDefault("1"),
+			np("memory", libcli.KindSize, "memory size").SetDefault("1G"),
+			np("disk-size", libcli.KindSize, "disk size").SetRequired(),
+			np("mac", libcli.KindMac, "mac address"),
+			np("ip", libcli.KindCidrv4, "ip address with mask").SetRequired(),
+			np("key", libcli.KindName, "key name").SetRequired().SetCompleter(completeKey(client)),
+			np("image", libcli.KindName, "base image name").SetRequired().SetCompleter(completeBaseImage(client)),
+			np("network", libcli.KindName, "network name").SetRequired().SetCompleter(completeNetwork(client)),
+			np("bridge", libcli.KindName, "bridge name").SetRequired(),
+		}, func(params map[string]string) error {
+			vcpu, err := strconv.ParseInt(params["cpu"], 10, 64)
 			if err != nil {
-				logger.Warn("failed to parse vcpu: %v", err)
-				return
+				cliLogger.Warn("failed to parse vcpu: %v", err)
+				return err
 			}
 
 			_, err = client.CreateDomain(context.Background(), &vmer.DomainMessage{
-				Name:       args[3],
+				Name:       params["name"],
 				Vcpu:       vcpu,
-				Memory:     args[7],
-				Mac:        args[11],
-				Ip:         args[13],
-				Key:        &vmer.KeyMessage{Name: args[15]},
-				DiskSize:   args[9],
-				Origin:     &vmer.BaseImageMessage{Name: args[17]},
-				Network:    &vmer.NetworkMessage{Name: args[19]},
-				BridgeName: args[21],
+				Memory:     params["memory"],
+				Mac:        params["mac"],
+				Ip:         params["ip"],
+				Key:        &vmer.KeyMessage{Name: params["key"]},
+				DiskSize:   params["disk-size"],
+				Origin:     &vmer.BaseImageMessage{Name: params["image"]},
+				Network:    &vmer.NetworkMessage{Name: params["network"]},
+				BridgeName: params["bridge"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 
-	// create domain by name cpu memory distsize ip key volume network
+	// delete domain
 	cli.AddCommandElem(
 		nce("domain", "domain"),
-		nce("create", "create domain"),
-		nce("name", "domain name"),
-		nce(libutil.NameRegex, "domain name"),
-		nce("cpu", "cpu number"),
-		nce(libutil.NumberRegex, "cpu number"),
-		nce("memory", "memory size"),
-		nce(libutil.UnitRegex, "memory size"),
-		nce("disk-size", "disk size"),
-		nce(libutil.UnitRegex, "disk size"),
-		nce("ip", "ip address with mask"),
-		nce(libutil.CidrRegex, "ip address"),
-		nce("key", "key name"),
-		nce(libutil.NameRegex, "key name"),
-		nce("image", "image name"),
-		nce(libutil.NameRegex, "base image name"),
-		nce("network", "network name"),
-		nce(libutil.NameRegex, ""),
-		nce("bridge", "bridge name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
-			vcpu, err := strconv.ParseInt(args[5], 10, 64)
+		ncep("delete", "delete domain", []*libcli.Param{
+			np("name", libcli.KindName, "domain name").SetRequired().SetCompleter(completeDomain(client)),
+		}, func(params map[string]string) error {
+			_, err := client.DeleteDomain(context.Background(), &vmer.DomainMessage{Name: params["name"]})
 			if err != nil {
-				logger.Warn("failed to parse vcpu: %v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
+		}))
 
-			_, err = client.CreateDomain(context.Background(), &vmer.DomainMessage{
-				Name:       args[3],
-				Vcpu:       vcpu,
-				Memory:     args[7],
-				DiskSize:   args[9],
-				Ip:         args[11],
-				Key:        &vmer.KeyMessage{Name: args[13]},
-				Origin:     &vmer.BaseImageMessage{Name: args[15]},
-				Network:    &vmer.NetworkMessage{Name: args[17]},
-				BridgeName: args[19],
-			})
+	// start domain
+	cli.AddCommandElem(
+		nce("domain", "domain"),
+		ncep("start", "start domain", []*libcli.Param{
+			np("name", libcli.KindName, "domain name").SetRequired().SetCompleter(completeDomain(client)),
+		}, func(params map[string]string) error {
+			_, err := client.StartDomain(context.Background(), &vmer.DomainMessage{Name: params["name"]})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 
-	// delete domain
+	// stop domain
 	cli.AddCommandElem(
 		nce("domain", "domain"),
-		nce("delete", "delete domain"),
-		nce("name", "domain name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
-			_, err := client.DeleteDomain(context.Background(), &vmer.DomainMessage{Name: args[3]})
+		ncep("stop", "stop domain", []*libcli.Param{
+			np("name", libcli.KindName, "domain name").SetRequired().SetCompleter(completeDomain(client)),
+		}, func(params map[string]string) error {
+			_, err := client.StopDomain(context.Background(), &vmer.DomainMessage{Name: params["name"]})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 
-	// start domain
+	// enter domain mode. eg. # domain web01 → (domain:web01)#
 	cli.AddCommandElem(
 		nce("domain", "domain"),
-		nce("start", "start domain"),
-		nce("name", "domain name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
-			_, err := client.StartDomain(context.Background(), &vmer.DomainMessage{Name: args[3]})
+		ncaf(libcli.KindName, "enter domain mode", func(args []string) error {
+			_, err := getDomain(client, args[1])
+			return err
+		}).SetMode("domain").SetCompleter(completeDomain(client)))
+
+	// show domain in domain mode
+	cli.AddCommandElem(
+		nce("domain", "domain"),
+		nca(libcli.KindName, "enter domain mode"),
+		ncef("show", "show domain", func(args []string) error {
+			domain, err := getDomain(client, args[1])
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				return err
 			}
+
+			cli.PrintStructOne(domain)
+			return nil
 		}))
 
-	// stop domain
+	// set cpu in domain mode
+	cli.AddCommandElem(
+		nce("domain", "domain"),
+		nca(libcli.KindName, "enter domain mode"),
+		nce("cpu", "set cpu number"),
+		ncaf(libcli.KindInt(1, 256), "cpu number", func(args []string) error {
+			vcpu, _ := strconv.ParseInt(args[3], 10, 64)
+			_, err := client.UpdateDomain(context.Background(), &vmer.DomainMessage{Name: args[1], Vcpu: vcpu})
+			if err != nil {
+				cliLogger.Warn("%v", err)
+				return err
+			}
+			return nil
+		}))
+
+	// set memory in domain mode
 	cli.AddCommandElem(
 		nce("domain", "domain"),
-		nce("stop", "stop domain"),
-		nce("name", "domain name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
-			_, err := client.StopDomain(context.Background(), &vmer.DomainMessage{Name: args[3]})
+		nca(libcli.KindName, "enter domain mode"),
+		nce("memory", "set memory size"),
+		ncaf(libcli.KindSize, "memory size", func(args []string) error {
+			_, err := client.UpdateDomain(context.Background(), &vmer.DomainMessage{Name: args[1], Memory: args[3]})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 
+	// start, stop and delete domain in domain mode
+	for _, action := range []struct {
+		name string
+		desc string
+		f    func(ctx context.Context, in *vmer.DomainMessage, opts ...grpc.CallOption) (*vmer.DomainMessage, error)
+	}{
+		{"start", "start domain", client.StartDomain},
+		{"stop", "stop domain", client.StopDomain},
+		{"delete", "delete domain", client.DeleteDomain},
+	} {
+		f := action.f
+		cli.AddCommandElem(
+			nce("domain", "domain"),
+			nca(libcli.KindName, "enter domain mode"),
+			ncef(action.name, action.desc, func(args []string) error {
+				_, err := f(context.Background(), &vmer.DomainMessage{Name: args[1]})
+				if err != nil {
+					cliLogger.Warn("%v", err)
+					return err
+				}
+				return nil
+			}))
+	}
+
 	// reboot domain
 
 	// attach volume
@@ -177,6 +205,48 @@ func initDomainCli(cli *libcli.GoCli) {
 	// detach volume
 }
 
+// get domain by name
+func getDomain(client vmer.VmerClient, name string) (*vmer.DomainMessage, error) {
+	stream, err := client.ShowDomains(context.Background(), &vmer.DomainMessage{Name: name})
+	if err != nil {
+		cliLogger.Warn("%v", err)
+		return nil, err
+	}
+
+	var streamInterface StreamInterface[*vmer.DomainMessage] = stream
+	messages, err := recvStream(streamInterface)
+	if err != nil {
+		cliLogger.Warn("%v", err)
+		return nil, err
+	}
+
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("domain \"%s\" does not exist", name)
+	}
+
+	return messages[0], nil
+}
+
+// complete domain names
+func completeDomain(client vmer.VmerClient) func(args []string) []string {
+	return func(args []string) []string {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		stream, err := client.ShowDomains(ctx, &vmer.DomainMessage{})
+		if err != nil {
+			return nil
+		}
+
+		var streamInterface StreamInterface[*vmer.DomainMessage] = stream
+		messages, err := recvStream(streamInterface)
+		if err != nil {
+			return nil
+		}
+
+		return getNames(messages)
+	}
+}
+
 // show domain
 func (s *server) ShowDomains(in *vmer.DomainMessage, stream vmer.Vmer_ShowDomainsServer) error {
 	var domains []Domain
@@ -197,7 +267,7 @@ func (s *server) ShowDomains(in *vmer.DomainMessage, stream vmer.Vmer_ShowDomain
 		return err
 	}
 
-	libvirtConn, err := libvirt.NewConnect("qemu:///system")
+	libvirtConn, err := libvirt.NewConnect(vmConfig.LibvirtUri)
 	if err != nil {
 		logger.Warn("failed to connect to libvirt: %v", err)
 		return err
@@ -254,7 +324,7 @@ func (s *server) CreateDomain(ctx context.Context, in *vmer.DomainMessage) (*vme
 		return in, err
 	}
 
-	domainBootVolumePath := fmt.Sprintf("%s/%s.qcow2", "/var/local/libvirt/volume", in.Name)
+	domainBootVolumePath := fmt.Sprintf("%s/%s.qcow2", vmConfig.VolumeDir, in.Name)
 
 	// copy base image file to volume path
 	_, err = libutil.CopyFile(baseImageFile, domainBootVolumePath)
@@ -328,7 +398,7 @@ func (s *server) CreateDomain(ctx context.Context, in *vmer.DomainMessage) (*vme
 	}
 
 	// make image
-	out, err = libutil.RunExternalImageMaker(domainBootVolumePath, key.Username, key.Rsa, mac, network.Vlan,
+	out, err = libutil.RunExternalImageMaker(vmConfig.ImageMaker, domainBootVolumePath, key.Username, key.Rsa, mac, network.Vlan,
 		in.Ip, network.Gateway, network.Dns)
 	if err != nil {
 		logger.Warn("failed to make image: %v, out: %s", err, out)
@@ -342,7 +412,7 @@ func (s *server) CreateDomain(ctx context.Context, in *vmer.DomainMessage) (*vme
 		return nil, err
 	}
 
-	libvirtConn, err := libvirt.NewConnect("qemu:///system")
+	libvirtConn, err := libvirt.NewConnect(vmConfig.LibvirtUri)
 	if err != nil {
 		logger.Warn("failed to connect to libvirt: %v", err)
 		return nil, err
@@ -389,7 +459,7 @@ func (s *server) DeleteDomain(ctx context.Context, in *vmer.DomainMessage) (*vme
 		return nil, err
 	}
 
-	libvirtConn, err := libvirt.NewConnect("qemu:///system")
+	libvirtConn, err := libvirt.NewConnect(vmConfig.LibvirtUri)
 	if err != nil {
 		logger.Warn("failed to connect to libvirt: %v", err)
 		return nil, err
@@ -435,7 +505,7 @@ func (s *server) StartDomain(ctx context.Context, in *vmer.DomainMessage) (*vmer
 		return nil, err
 	}
 
-	libvirtConn, err := libvirt.NewConnect("qemu:///system")
+	libvirtConn, err := libvirt.NewConnect(vmConfig.LibvirtUri)
 	if err != nil {
 		logger.Warn("failed to connect to libvirt: %v", err)
 		return nil, err
@@ -461,6 +531,74 @@ func (s *server) StartDomain(ctx context.Context, in *vmer.DomainMessage) (*vmer
 	return in, nil
 }
 
+// update cpu and memory of domain. changes are applied on next boot
+func (s *server) UpdateDomain(ctx context.Context, in *vmer.DomainMessage) (*vmer.DomainMessage, error) {
+	// get domain
+	domain, err := vmerDB.GetDomainByName(in.Name)
+	if err != nil {
+		logger.Warn("failed to get domain: %v", err)
+		return nil, err
+	}
+
+	libvirtConn, err := libvirt.NewConnect(vmConfig.LibvirtUri)
+	if err != nil {
+		logger.Warn("failed to connect to libvirt: %v", err)
+		return nil, err
+	}
+	defer libvirtConn.Close()
+
+	libvirtDomain, err := libvirtConn.LookupDomainByName(domain.Name)
+	if err != nil {
+		logger.Warn("failed to lookup domain: %v", err)
+		return nil, err
+	}
+
+	if in.Vcpu > 0 {
+		// maximum is raised before current and lowered after current
+		flagSlice := []libvirt.DomainVcpuFlags{libvirt.DOMAIN_VCPU_CONFIG, libvirt.DOMAIN_VCPU_CONFIG | libvirt.DOMAIN_VCPU_MAXIMUM}
+		if in.Vcpu > domain.Cpu {
+			flagSlice[0], flagSlice[1] = flagSlice[1], flagSlice[0]
+		}
+
+		for _, flags := range flagSlice {
+			if err := libvirtDomain.SetVcpusFlags(uint(in.Vcpu), flags); err != nil {
+				logger.Warn("failed to set vcpu: %v", err)
+				return nil, err
+			}
+		}
+		domain.Cpu = in.Vcpu
+	}
+
+	if in.Memory != "" {
+		memory, err := libutil.ConvertSizeToBytes(in.Memory)
+		if err != nil {
+			logger.Warn("failed to convert memory: %v", err)
+			return nil, err
+		}
+
+		// KiB
+		flagSlice := []libvirt.DomainMemoryModFlags{libvirt.DOMAIN_MEM_CONFIG, libvirt.DOMAIN_MEM_CONFIG | libvirt.DOMAIN_MEM_MAXIMUM}
+		if memory > domain.Memory {
+			flagSlice[0], flagSlice[1] = flagSlice[1], flagSlice[0]
+		}
+
+		for _, flags := range flagSlice {
+			if err := libvirtDomain.SetMemoryFlags(uint64(memory/1024), flags); err != nil {
+				logger.Warn("failed to set memory: %v", err)
+				return nil, err
+			}
+		}
+		domain.Memory = memory
+	}
+
+	if err := vmerDB.UpdateDomain(domain); err != nil {
+		logger.Warn("failed to update domain: %v", err)
+		return nil, err
+	}
+
+	return in, nil
+}
+
 // stop domain
 func (s *server) StopDomain(ctx context.Context, in *vmer.DomainMessage) (*vmer.DomainMessage, error) {
 	// get domain
@@ -470,7 +608,7 @@ func (s *server) StopDomain(ctx context.Context, in *vmer.DomainMessage) (*vmer.
 		return nil, err
 	}
 
-	libvirtConn, err := libvirt.NewConnect("qemu:///system")
+	libvirtConn, err := libvirt.NewConnect(vmConfig.LibvirtUri)
 	if err != nil {
 		logger.Warn("failed to connect to libvirt: %v", err)
 		return nil, err
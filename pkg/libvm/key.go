@@ -5,91 +5,92 @@ import (
 	"go-cli/pkg/libcli"
 	"go-cli/pkg/libutil"
 	"go-cli/pkg/libvm/vmer"
+	"time"
 )
 
 // init cli
-func initKeyCli(cli *libcli.GoCli) {
-	// show key
+func initKeyCli(cli *libcli.GoCli, client vmer.VmerClient) {
+	// show keys. key of name if name is set
 	cli.AddCommandElem(
 		nce("key", "key"),
-		ncef("show", "show key", func(args []string) {
-			stream, err := client.ShowKeys(context.Background(), &vmer.KeyMessage{})
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			var streamInterface StreamInterface[*vmer.KeyMessage] = stream
-
-			messages, err := recvStream(streamInterface)
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			libutil.PrintStructAll(messages)
-		}))
-
-	// show key by name
-	cli.AddCommandElem(
-		nce("key", "key"),
-		nce("show", "show key"),
-		nce("name", "show key by name"),
-		ncef(libutil.NameRegex, "show key by name", func(args []string) {
+		ncep("show", "show key", []*libcli.Param{
+			np("name", libcli.KindName, "key name").SetCompleter(completeKey(client)),
+		}, func(params map[string]string) error {
 			stream, err := client.ShowKeys(context.Background(), &vmer.KeyMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+
 			var streamInterface StreamInterface[*vmer.KeyMessage] = stream
 
 			messages, err := recvStream(streamInterface)
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
-			libutil.PrintStructAll(messages)
+			cli.PrintStructAll(messages)
+			return nil
 		}))
 
-	// upload key
+	// upload key by name username path in any order
 	cli.AddCommandElem(
 		nce("key", "key"),
-		nce("upload", "upload key"),
-		nce("name", "upload key by name"),
-		nce(libutil.NameRegex, "upload key by name"),
-		nce("username", "upload key by username"),
-		nce(libutil.NameRegex, "upload key by name"),
-		nce("path", "upload key by path"),
-		ncef(libutil.FilePathRegex, "upload key by name", func(args []string) {
+		ncep("upload", "upload key", []*libcli.Param{
+			np("name", libcli.KindName, "key name").SetRequired(),
+			np("username", libcli.KindName, "username of key").SetRequired(),
+			np("path", libcli.KindExistPath, "public key path").SetRequired(),
+		}, func(params map[string]string) error {
 			_, err := client.UploadKey(context.Background(), &vmer.KeyMessage{
-				Name:     args[3],
-				Username: args[5],
-				Path:     args[7],
+				Name:     params["name"],
+				Username: params["username"],
+				Path:     params["path"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 
 	// delete key by name
 	cli.AddCommandElem(
 		nce("key", "key"),
-		nce("delete", "delete key"),
-		nce("name", "delete key by name"),
-		ncef(libutil.NameRegex, "delete key by name", func(args []string) {
+		ncep("delete", "delete key", []*libcli.Param{
+			np("name", libcli.KindName, "key name").SetRequired().SetCompleter(completeKey(client)),
+		}, func(params map[string]string) error {
 			_, err := client.DeleteKey(context.Background(), &vmer.KeyMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
+}
 
+// complete key names
+func completeKey(client vmer.VmerClient) func(args []string) []string {
+	return func(args []string) []string {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		stream, err := client.ShowKeys(ctx, &vmer.KeyMessage{})
+		if err != nil {
+			return nil
+		}
+
+		var streamInterface StreamInterface[*vmer.KeyMessage] = stream
+		messages, err := recvStream(streamInterface)
+		if err != nil {
+			return nil
+		}
+
+		return getNames(messages)
+	}
 }
 
 // show key
@@ -3,106 +3,105 @@ package libvm
 import (
 	"context"
 	"go-cli/pkg/libcli"
-	"go-cli/pkg/libutil"
 	"go-cli/pkg/libvm/vmer"
 	"strconv"
+	"time"
 )
 
-func initNetworkCli(cli *libcli.GoCli) {
-	// show network
+func initNetworkCli(cli *libcli.GoCli, client vmer.VmerClient) {
+	// show networks. network of name if name is set
 	cli.AddCommandElem(
 		nce("network", "network"),
-		ncef("show", "show network", func(args []string) {
-			stream, err := client.ShowNetworks(context.Background(), &vmer.NetworkMessage{})
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			var streamInterface StreamInterface[*vmer.NetworkMessage] = stream
-
-			messages, err := recvStream(streamInterface)
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			libutil.PrintStructAll(messages)
-		}))
-
-	// show network by name
-	cli.AddCommandElem(
-		nce("network", "network"),
-		nce("show", "show network"),
-		nce("name", "network name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
+		ncep("show", "show network", []*libcli.Param{
+			np("name", libcli.KindName, "network name").SetCompleter(completeNetwork(client)),
+		}, func(params map[string]string) error {
 			stream, err := client.ShowNetworks(context.Background(), &vmer.NetworkMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+
 			var streamInterface StreamInterface[*vmer.NetworkMessage] = stream
 
 			messages, err := recvStream(streamInterface)
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
-			libutil.PrintStructAll(messages)
+			cli.PrintStructAll(messages)
+			return nil
 		}))
 
-	// create network
+	// create network by name vlan cidr gateway dns in any order
 	cli.AddCommandElem(
 		nce("network", "network"),
-		nce("create", "create network"),
-		nce("name", "network name"),
-		nce(libutil.NameRegex, ""),
-		nce("vlan", "vlan"),
-		nce(libutil.NumberRegex, ""),
-		nce("cidr", "cidr"),
-		nce(libutil.CidrRegex, ""),
-		nce("gateway", "gateway"),
-		nce(libutil.IpRegex, ""),
-		nce("dns", "dns"),
-		ncef(libutil.IpRegex, "", func(args []string) {
-			vlanId, err := strconv.ParseInt(args[5], 10, 32)
+		ncep("create", "create network", []*libcli.Param{
+			np("name", libcli.KindName, "network name").SetRequired(),
+			np("vlan", libcli.KindInt(0, 4094), "vlan").SetRequired(),
+			np("cidr", libcli.KindCidrv4, "cidr").SetRequired(),
+			np("gateway", libcli.KindIpv4, "gateway").SetRequired(),
+			np("dns", libcli.KindIpv4, "dns").SetRequired(),
+		}, func(params map[string]string) error {
+			vlanId, err := strconv.ParseInt(params["vlan"], 10, 32)
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
 			_, err = client.CreateNetwork(context.Background(), &vmer.NetworkMessage{
-				Name:    args[3],
+				Name:    params["name"],
 				Vlan:    int32(vlanId),
-				Cidr:    args[7],
-				Gateway: args[9],
-				Dns:     args[11],
+				Cidr:    params["cidr"],
+				Gateway: params["gateway"],
+				Dns:     params["dns"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 
 	// delete network
 	cli.AddCommandElem(
 		nce("network", "network"),
-		nce("delete", "delete network"),
-		nce("name", "network name"),
-		ncef(libutil.NameRegex, "", func(args []string) {
+		ncep("delete", "delete network", []*libcli.Param{
+			np("name", libcli.KindName, "network name").SetRequired().SetCompleter(completeNetwork(client)),
+		}, func(params map[string]string) error {
 			_, err := client.DeleteNetwork(context.Background(), &vmer.NetworkMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 }
 
+// complete network names
+func completeNetwork(client vmer.VmerClient) func(args []string) []string {
+	return func(args []string) []string {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		stream, err := client.ShowNetworks(ctx, &vmer.NetworkMessage{})
+		if err != nil {
+			return nil
+		}
+
+		var streamInterface StreamInterface[*vmer.NetworkMessage] = stream
+		messages, err := recvStream(streamInterface)
+		if err != nil {
+			return nil
+		}
+
+		return getNames(messages)
+	}
+}
+
 // show network
 func (s *server) ShowNetworks(in *vmer.NetworkMessage, stream vmer.Vmer_ShowNetworksServer) error {
 	var networks []Network
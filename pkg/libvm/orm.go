@@ -1,6 +1,8 @@
 package libvm
 
 import (
+	"go-cli/pkg/libutil"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -72,7 +74,7 @@ func NewVmerDB(path string) (*VmerDB, error) {
 
 // vmer open db by path
 func (vmerDB *VmerDB) Open(path string) error {
-	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(libutil.GetSqliteDsn(path)), &gorm.Config{})
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,29 @@
+package libvm
+
+import "go-cli/pkg/libutil"
+
+// required role of vmer methods
+var permissions = map[string]libutil.Role{
+	"ShowBaseImages": libutil.RoleViewer,
+	"ShowKeys":       libutil.RoleViewer,
+	"ShowNetworks":   libutil.RoleViewer,
+	"ShowVolumes":    libutil.RoleViewer,
+	"ShowDomains":    libutil.RoleViewer,
+
+	"CreateVolume": libutil.RoleOperator,
+	"CreateDomain": libutil.RoleOperator,
+	"StartDomain":  libutil.RoleOperator,
+	"StopDomain":   libutil.RoleOperator,
+	"UpdateDomain": libutil.RoleOperator,
+
+	"UploadBaseImage": libutil.RoleAdmin,
+	"DeleteBaseImage": libutil.RoleAdmin,
+	"UploadKey":       libutil.RoleAdmin,
+	"CreateKey":       libutil.RoleAdmin,
+	"DeleteKey":       libutil.RoleAdmin,
+	"CreateNetwork":   libutil.RoleAdmin,
+	"DeleteNetwork":   libutil.RoleAdmin,
+	"DeleteVolume":    libutil.RoleAdmin,
+	"DeleteDomain":    libutil.RoleAdmin,
+	"ShowAudits":      libutil.RoleAdmin,
+}
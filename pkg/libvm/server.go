@@ -1,58 +1,105 @@
 package libvm
 
 import (
-	"fmt"
+	"errors"
 	"go-cli/pkg/libutil"
 	"go-cli/pkg/libvm/vmer"
 	"log"
-	"net"
-	"os"
+	"sync"
 
 	nblogger "github.com/banaconda/nb-logger"
+	"github.com/mattn/go-sqlite3"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"gorm.io/gorm"
 )
 
 var logger nblogger.Logger
+
+// grpc server is set by serving goroutine and stopped by others
+var grpcServer *grpc.Server
+var grpcServerMutex sync.Mutex
 var vmerDB *VmerDB
+var auditDB *libutil.AuditDB
+
+// volume dir, libvirt uri and image maker
+var vmConfig *libutil.Config
 
 type server struct {
 	vmer.UnimplementedVmerServer
 }
 
-func handlerRequests(host string, port int) {
-	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", host, port))
+// get grpc code of db error. eg. not found of record, already exists of unique name
+func getErrorCode(err error) codes.Code {
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return codes.NotFound
+	case errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique:
+		return codes.AlreadyExists
+	}
+
+	return codes.Unknown
+}
+
+func handlerRequests(network string, address string, opts ...grpc.ServerOption) {
+	lis, err := libutil.Listen(network, address)
 	if err != nil {
 		logger.Error("failed to listen: %v", err)
 		panic(err)
 	}
 
-	s := grpc.NewServer()
+	newServer := grpc.NewServer(opts...)
+	vmer.RegisterVmerServer(newServer, &server{})
+	grpcServerMutex.Lock()
+	grpcServer = newServer
+	grpcServerMutex.Unlock()
 
-	vmer.RegisterVmerServer(s, &server{})
 	logger.Info("server listening at %v", lis.Addr())
-	if err := s.Serve(lis); err != nil {
+	if err := newServer.Serve(lis); err != nil {
 		logger.Error("failed to serve: %v", err)
 		panic(err)
 	}
 }
 
-func VmServer() {
-	logPath := "log/vm.log"
-	if _, err := os.Stat("log/"); os.IsNotExist(err) {
-		os.MkdirAll("log", 0700)
-	}
+// serve vmer on address of config. eg. unix /run/go-cli/vm.sock
+// mutating calls are recorded to audit db, which is shown by vmer
+func VmServer(config *libutil.Config, audit *libutil.AuditDB) {
+	vmConfig = config
+	auditDB = audit
 
 	var err error
-	logger, err = nblogger.NewLogger(logPath, nblogger.Info, 1000, nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
+	logger, err = nblogger.NewLogger(config.GetLogPath("vm.log"), nblogger.Info, 1000, nblogger.LstdFlags|nblogger.Lshortfile|nblogger.Lmicroseconds)
 	if err != nil {
 		log.Fatalf("logger init fail: %v", err)
 	}
 
-	vmerDB, err = NewVmerDB("local.db")
+	vmerDB, err = NewVmerDB(config.DbPath)
 	if err != nil {
 		logger.Error("failed to open vmer db: %v", err)
 		return
 	}
 
-	handlerRequests("", libutil.VM_PORT)
+	opts, err := config.GetServerOptions()
+	if err != nil {
+		logger.Error("failed to load tls: %v", err)
+		return
+	}
+	opts = append(opts, audit.GetAuditOptions(logger, config)...)
+	opts = append(opts, config.GetAuthorizeOptions(logger, permissions)...)
+	opts = append(opts, libutil.GetStatusOptions(getErrorCode)...)
+
+	network, address := config.GetVmAddress()
+	handlerRequests(network, address, opts...)
+}
+
+// stop VmServer gracefully after pending requests are done
+func VmServerStop() {
+	grpcServerMutex.Lock()
+	stopServer := grpcServer
+	grpcServerMutex.Unlock()
+
+	if stopServer != nil {
+		stopServer.GracefulStop()
+	}
 }
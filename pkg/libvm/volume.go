@@ -6,96 +6,97 @@ import (
 	"go-cli/pkg/libutil"
 	"go-cli/pkg/libvm/vmer"
 	"os"
+	"time"
 )
 
-func initVolumeCli(cli *libcli.GoCli) {
-	// show volumes
+func initVolumeCli(cli *libcli.GoCli, client vmer.VmerClient) {
+	// show volumes. volume of name if name is set
 	cli.AddCommandElem(
 		nce("volume", "volume"),
-		ncef("show", "show volumes", func(args []string) {
-			stream, err := client.ShowVolumes(context.Background(), &vmer.VolumeMessage{})
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			var streamInterface StreamInterface[*vmer.VolumeMessage] = stream
-
-			messages, err := recvStream(streamInterface)
-			if err != nil {
-				logger.Warn("%v", err)
-				return
-			}
-
-			libutil.PrintStructAll(messages)
-		}))
-
-	// show volume by name
-	cli.AddCommandElem(
-		nce("volume", "volume"),
-		nce("show", "show volume by name"),
-		nce("name", "volume name"),
-		ncef("show", "show volume by name", func(args []string) {
+		ncep("show", "show volumes", []*libcli.Param{
+			np("name", libcli.KindName, "volume name").SetCompleter(completeVolume(client)),
+		}, func(params map[string]string) error {
 			stream, err := client.ShowVolumes(context.Background(), &vmer.VolumeMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+
 			var streamInterface StreamInterface[*vmer.VolumeMessage] = stream
 
 			messages, err := recvStream(streamInterface)
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
 
-			libutil.PrintStructAll(messages)
+			cli.PrintStructAll(messages)
+			return nil
 		}))
 
-	// create volume
+	// create volume by name path size origin in any order
 	cli.AddCommandElem(
 		nce("volume", "volume"),
-		nce("create", "create volume"),
-		nce("name", "volume name"),
-		nce(libutil.NameRegex, "volume name"),
-		nce("path", "volume path"),
-		nce(libutil.FilePathRegex, "volume path"),
-		nce("size", "volume size"),
-		nce(libutil.UnitRegex, "volume size"),
-		nce("origin", "volume origin"),
-		ncef(libutil.NameRegex, "volume name", func(args []string) {
+		ncep("create", "create volume", []*libcli.Param{
+			np("name", libcli.KindName, "volume name").SetRequired(),
+			np("path", libcli.KindPath, "volume path").SetRequired(),
+			np("size", libcli.KindSize, "volume size").SetRequired(),
+			np("origin", libcli.KindName, "base image name").SetRequired().SetCompleter(completeBaseImage(client)),
+		}, func(params map[string]string) error {
 			_, err := client.CreateVolume(context.Background(), &vmer.VolumeMessage{
-				Name: args[3],
-				Path: args[5],
-				Size: args[7],
+				Name: params["name"],
+				Path: params["path"],
+				Size: params["size"],
 				Origin: &vmer.BaseImageMessage{
-					Name: args[9],
+					Name: params["origin"],
 				},
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 
 	// delete volume by name
 	cli.AddCommandElem(
 		nce("volume", "volume"),
-		nce("delete", "delete volume by name"),
-		nce("name", "volume name"),
-		ncef(libutil.NameRegex, "volume name", func(args []string) {
+		ncep("delete", "delete volume by name", []*libcli.Param{
+			np("name", libcli.KindName, "volume name").SetRequired().SetCompleter(completeVolume(client)),
+		}, func(params map[string]string) error {
 			_, err := client.DeleteVolume(context.Background(), &vmer.VolumeMessage{
-				Name: args[3],
+				Name: params["name"],
 			})
 			if err != nil {
-				logger.Warn("%v", err)
-				return
+				cliLogger.Warn("%v", err)
+				return err
 			}
+			return nil
 		}))
 }
 
+// complete volume names
+func completeVolume(client vmer.VmerClient) func(args []string) []string {
+	return func(args []string) []string {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		stream, err := client.ShowVolumes(ctx, &vmer.VolumeMessage{})
+		if err != nil {
+			return nil
+		}
+
+		var streamInterface StreamInterface[*vmer.VolumeMessage] = stream
+		messages, err := recvStream(streamInterface)
+		if err != nil {
+			return nil
+		}
+
+		return getNames(messages)
+	}
+}
+
 // show volumes
 func (s *server) ShowVolumes(in *vmer.VolumeMessage, stream vmer.Vmer_ShowVolumesServer) error {
 	var volumes []Volume